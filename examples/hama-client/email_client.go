@@ -5,14 +5,72 @@ package clients
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	emailv1 "github.com/Cloud9Money/maia/proto/email/v1"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
+// Options customizes SendVerificationEmail and SendPasswordResetEmail's
+// generated link and locale. RedirectTo is validated server-side against
+// Valar's allowed_redirect_hosts; AllowedRedirectHosts, if set, narrows
+// that check further but can never expand past what the server allows.
+type Options struct {
+	RedirectTo           string
+	Locale               string
+	AllowedRedirectHosts []string
+	Metadata             map[string]string
+}
+
+func (o Options) toProto() *emailv1.Options {
+	if o.RedirectTo == "" && o.Locale == "" && len(o.AllowedRedirectHosts) == 0 && len(o.Metadata) == 0 {
+		return nil
+	}
+	return &emailv1.Options{
+		RedirectTo:           o.RedirectTo,
+		Locale:               o.Locale,
+		AllowedRedirectHosts: o.AllowedRedirectHosts,
+		Metadata:             o.Metadata,
+	}
+}
+
+// Sentinel errors decoded from the typed details Valar attaches to
+// FailedPrecondition/InvalidArgument/AlreadyExists responses (see
+// errorWithDetail in Valar's EmailServer), so callers can use errors.Is
+// instead of matching on the gRPC status message string.
+var (
+	ErrEmailAlreadyVerified = errors.New("clients: email is already verified")
+	ErrEmailAlreadyInUse    = errors.New("clients: email is already in use")
+	ErrDisabledUser         = errors.New("clients: account is disabled")
+	ErrInvalidRedirect      = errors.New("clients: redirect_to is not an allowed host")
+)
+
+// decodeSendError maps a gRPC error's details onto one of the sentinel
+// errors above. Errors without a recognized detail are returned unchanged.
+func decodeSendError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	for _, detail := range st.Details() {
+		switch detail.(type) {
+		case *emailv1.EmailAlreadyVerifiedDetail:
+			return ErrEmailAlreadyVerified
+		case *emailv1.EmailAlreadyInUseDetail:
+			return ErrEmailAlreadyInUse
+		case *emailv1.DisabledUserDetail:
+			return ErrDisabledUser
+		case *emailv1.InvalidRedirectDetail:
+			return ErrInvalidRedirect
+		}
+	}
+	return err
+}
+
 // EmailClient wraps the gRPC client for email operations
 type EmailClient struct {
 	client  emailv1.EmailServiceClient
@@ -49,19 +107,24 @@ func (c *EmailClient) Close() error {
 	return c.conn.Close()
 }
 
-// SendVerificationEmail sends an email verification link to the user
-func (c *EmailClient) SendVerificationEmail(ctx context.Context, email, token, userName string) error {
+// SendVerificationEmail sends an email verification link to the user.
+// opts.RedirectTo, if set, must resolve to a host Valar allows (see
+// Options); a caller that's already verified gets back ErrEmailAlreadyVerified
+// instead of a resend.
+func (c *EmailClient) SendVerificationEmail(ctx context.Context, email, token, userName string, opts Options) error {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
 	resp, err := c.client.SendVerificationEmail(ctx, &emailv1.SendVerificationEmailRequest{
 		To:                email,
+		IdempotencyKey:    deriveIdempotencyKey("verify", email, token),
 		VerificationToken: token,
 		UserName:          userName,
+		Options:           opts.toProto(),
 	})
 
 	if err != nil {
-		return fmt.Errorf("gRPC call failed: %w", err)
+		return fmt.Errorf("gRPC call failed: %w", decodeSendError(err))
 	}
 
 	if !resp.Success {
@@ -71,20 +134,24 @@ func (c *EmailClient) SendVerificationEmail(ctx context.Context, email, token, u
 	return nil
 }
 
-// SendPasswordResetEmail sends a password reset email to the user
-func (c *EmailClient) SendPasswordResetEmail(ctx context.Context, email, resetToken, userName string, expiryMinutes int32) error {
+// SendPasswordResetEmail sends a password reset email to the user.
+// opts.RedirectTo, if set, must resolve to a host Valar allows (see
+// Options); a disabled account gets back ErrDisabledUser instead of a send.
+func (c *EmailClient) SendPasswordResetEmail(ctx context.Context, email, resetToken, userName string, expiryMinutes int32, opts Options) error {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
 	resp, err := c.client.SendPasswordResetEmail(ctx, &emailv1.SendPasswordResetEmailRequest{
-		To:            email,
-		ResetToken:    resetToken,
-		UserName:      userName,
-		ExpiryMinutes: expiryMinutes,
+		To:             email,
+		IdempotencyKey: deriveIdempotencyKey("reset", email, resetToken),
+		ResetToken:     resetToken,
+		UserName:       userName,
+		ExpiryMinutes:  expiryMinutes,
+		Options:        opts.toProto(),
 	})
 
 	if err != nil {
-		return fmt.Errorf("gRPC call failed: %w", err)
+		return fmt.Errorf("gRPC call failed: %w", decodeSendError(err))
 	}
 
 	if !resp.Success {
@@ -100,9 +167,10 @@ func (c *EmailClient) SendWelcomeEmail(ctx context.Context, email, userName, acc
 	defer cancel()
 
 	resp, err := c.client.SendWelcomeEmail(ctx, &emailv1.SendWelcomeEmailRequest{
-		To:          email,
-		UserName:    userName,
-		AccountType: accountType,
+		To:             email,
+		IdempotencyKey: deriveIdempotencyKey("welcome", email, accountType),
+		UserName:       userName,
+		AccountType:    accountType,
 	})
 
 	if err != nil {
@@ -123,6 +191,7 @@ func (c *EmailClient) SendTransactionNotification(ctx context.Context, email, tx
 
 	resp, err := c.client.SendTransactionNotification(ctx, &emailv1.SendTransactionNotificationRequest{
 		To:              email,
+		IdempotencyKey:  deriveIdempotencyKey("txn", txnID),
 		TransactionId:   txnID,
 		TransactionType: txnType,
 		Amount:          amount,
@@ -148,10 +217,11 @@ func (c *EmailClient) SendCustomEmail(ctx context.Context, to, subject, htmlBody
 	defer cancel()
 
 	resp, err := c.client.SendEmail(ctx, &emailv1.SendEmailRequest{
-		To:       to,
-		Subject:  subject,
-		HtmlBody: htmlBody,
-		TextBody: textBody,
+		To:             to,
+		IdempotencyKey: deriveIdempotencyKey("custom", to, subject, htmlBody, textBody),
+		Subject:        subject,
+		HtmlBody:       htmlBody,
+		TextBody:       textBody,
 	})
 
 	if err != nil {
@@ -162,20 +232,116 @@ func (c *EmailClient) SendCustomEmail(ctx context.Context, to, subject, htmlBody
 		return fmt.Errorf("email send failed: %s", resp.Error)
 	}
 
+	// resp.Provider reports which backend in Valar's chain actually
+	// delivered the message (e.g. "resend", "smtp", "mailgun"), useful
+	// for debugging deliverability issues per-provider.
 	return nil
 }
 
+// SendEmailWithKey sends a custom email under a caller-supplied
+// idempotency key, so Hama can safely retry on gRPC errors (timeouts,
+// transient unavailability) without risking a duplicate send: a retry
+// with the same key is not dispatched again. It returns the stored message
+// ID once the original was sent, and an empty one while the original is
+// still pending or waiting for a background retry.
+func (c *EmailClient) SendEmailWithKey(ctx context.Context, key, to, subject, htmlBody, textBody string) (messageID string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.SendEmail(ctx, &emailv1.SendEmailRequest{
+		To:             to,
+		IdempotencyKey: key,
+		Subject:        subject,
+		HtmlBody:       htmlBody,
+		TextBody:       textBody,
+	})
+	if err != nil {
+		return "", fmt.Errorf("gRPC call failed: %w", err)
+	}
+	if !resp.Success {
+		return "", fmt.Errorf("email send failed: %s", resp.Error)
+	}
+
+	return resp.MessageId, nil
+}
+
+// deliveryPollInterval is how often WaitForDelivery re-checks GetEmailStatus.
+const deliveryPollInterval = 500 * time.Millisecond
+
+// WaitForDelivery blocks until the outbox reports sent, failed or
+// dead_letter for key, or ctx is canceled. It returns on the first failed
+// status, although the outbox worker may still retry a failed message.
+func (c *EmailClient) WaitForDelivery(ctx context.Context, key string) (status, messageID string, err error) {
+	ticker := time.NewTicker(deliveryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		resp, err := c.client.GetEmailStatus(ctx, &emailv1.GetEmailStatusRequest{IdempotencyKey: key})
+		if err == nil {
+			switch resp.Status {
+			case "sent", "failed", "dead_letter":
+				return resp.Status, resp.MessageId, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", "", fmt.Errorf("waiting for delivery of %q: %w", key, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliveryEvent mirrors emailv1.DeliveryEvent for callers that don't want
+// to depend on the generated proto type directly.
+type DeliveryEvent struct {
+	MessageID string
+	Type      string // queued, sent, delivered, bounced, complained, opened
+	Timestamp int64
+}
+
+// WatchDelivery streams post-send delivery events (bounces, opens,
+// complaints, ...) for messageID until ctx is canceled, so Hama can react
+// in real time instead of relying on the boolean Success SendEmail*
+// returned - e.g. mark a user's email invalid on a bounce.
+func (c *EmailClient) WatchDelivery(ctx context.Context, messageID string) (<-chan DeliveryEvent, error) {
+	stream, err := c.client.StreamDeliveryEvents(ctx, &emailv1.StreamDeliveryEventsRequest{MessageId: messageID})
+	if err != nil {
+		return nil, fmt.Errorf("gRPC call failed: %w", err)
+	}
+
+	events := make(chan DeliveryEvent)
+	go func() {
+		defer close(events)
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			select {
+			case events <- DeliveryEvent{MessageID: event.MessageId, Type: event.Type, Timestamp: event.Timestamp}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
 // Example usage in Hama's auth handler
 /*
 package handlers
 
 import (
 	"context"
+	"errors"
 	"github.com/Cloud9Money/hama/internal/clients"
 )
 
 type AuthHandler struct {
-	emailClient *clients.EmailClient
+	emailClient        *clients.EmailClient
+	notificationClient *clients.NotificationClient
 	// ... other dependencies
 }
 
@@ -185,8 +351,14 @@ func NewAuthHandler(valarEndpoint string) (*AuthHandler, error) {
 		return nil, err
 	}
 
+	notificationClient, err := clients.NewNotificationClient(valarEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
 	return &AuthHandler{
-		emailClient: emailClient,
+		emailClient:        emailClient,
+		notificationClient: notificationClient,
 	}, nil
 }
 
@@ -210,8 +382,14 @@ func (h *AuthHandler) Register(ctx context.Context, req *RegisterRequest) error
 			user.Email,
 			token,
 			user.Name,
+			clients.Options{RedirectTo: req.RedirectTo},
 		)
 		if err != nil {
+			if errors.Is(err, clients.ErrEmailAlreadyVerified) {
+				// Not a failure: the account was already verified, so
+				// there's nothing to resend.
+				return
+			}
 			// Log error but don't fail registration
 			log.Error("Failed to send verification email", "error", err, "userID", user.ID)
 		}
@@ -233,16 +411,23 @@ func (h *AuthHandler) ForgotPassword(ctx context.Context, req *ForgotPasswordReq
 		return err
 	}
 
-	// 3. Send password reset email via gRPC
-	err = h.emailClient.SendPasswordResetEmail(
+	// 3. Send password reset via NotificationService instead of calling
+	// SendPasswordResetEmail directly: password resets are critical, so
+	// "all" mode sends through every channel the user has configured
+	// (email, SMS, Telegram), not only the first one that succeeds.
+	err = h.notificationClient.SendNotification(
 		ctx,
-		user.Email,
-		resetToken,
-		user.Name,
-		30, // 30 minutes expiry
+		"password_reset",
+		clients.Recipient{Email: user.Email, Phone: user.Phone, TelegramChatID: user.TelegramChatID},
+		map[string]string{
+			"user_name":  user.Name,
+			"reset_url":  fmt.Sprintf("https://app.cloud9.money/reset-password?token=%s", resetToken),
+		},
+		nil, // no explicit channel override: use the user's stored preferences
+		"all",
 	)
 	if err != nil {
-		// In this case, we want to fail the request if email fails
+		// In this case, we want to fail the request if every channel fails
 		return err
 	}
 
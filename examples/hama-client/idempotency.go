@@ -0,0 +1,50 @@
+package clients
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// newIdempotencyKey generates a random key for callers that don't have a
+// natural one of their own (e.g. a DB row ID) to dedupe retries against.
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// deriveIdempotencyKey hashes parts into a stable key, so a caller retrying
+// the same logical request (e.g. "resend this verification token") reuses
+// the same outbox row instead of minting a new one and sending twice.
+// Callers that truly have no stable inputs should use newIdempotencyKey
+// (or, for SendEmail, the explicit key SendEmailWithKey accepts) instead.
+func deriveIdempotencyKey(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0}) // separator, so ("ab","c") != ("a","bc")
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// sortedVariables renders variables as a stable "key=value" sequence so it
+// can be folded into deriveIdempotencyKey regardless of map iteration order.
+func sortedVariables(variables map[string]string) string {
+	keys := make([]string, 0, len(variables))
+	for k := range variables {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(variables[k])
+		b.WriteByte(';')
+	}
+	return b.String()
+}
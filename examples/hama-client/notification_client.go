@@ -0,0 +1,84 @@
+package clients
+
+// Example gRPC client implementation for Hama (consuming Valar's
+// NotificationService) - lets callers send a template-driven notification
+// without caring which backend (email, SMS, Telegram) actually delivers it.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	notificationv1 "github.com/Cloud9Money/maia/proto/notification/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// NotificationClient wraps the gRPC client for channel-agnostic notifications.
+type NotificationClient struct {
+	client  notificationv1.NotificationServiceClient
+	conn    *grpc.ClientConn
+	timeout time.Duration
+}
+
+// NewNotificationClient creates a new notification client connected to Valar.
+func NewNotificationClient(valarEndpoint string) (*NotificationClient, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := grpc.DialContext(
+		ctx,
+		valarEndpoint,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithBlock(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Valar at %s: %w", valarEndpoint, err)
+	}
+
+	return &NotificationClient{
+		client:  notificationv1.NewNotificationServiceClient(conn),
+		conn:    conn,
+		timeout: 10 * time.Second,
+	}, nil
+}
+
+// Close closes the gRPC connection.
+func (c *NotificationClient) Close() error {
+	return c.conn.Close()
+}
+
+// SendNotification dispatches templateKey to whichever channels the
+// recipient has configured (or channels, if explicitly given), in mode
+// ("fallback" or "all").
+func (c *NotificationClient) SendNotification(ctx context.Context, templateKey string, recipient Recipient, variables map[string]string, channels []string, mode string) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.SendNotification(ctx, &notificationv1.SendNotificationRequest{
+		TemplateKey:    templateKey,
+		Email:          recipient.Email,
+		Phone:          recipient.Phone,
+		TelegramChatId: recipient.TelegramChatID,
+		Variables:      variables,
+		Channels:       channels,
+		Mode:           mode,
+		IdempotencyKey: deriveIdempotencyKey(templateKey, recipient.Email, recipient.Phone, recipient.TelegramChatID, sortedVariables(variables)),
+	})
+	if err != nil {
+		return fmt.Errorf("gRPC call failed: %w", err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("notification send failed on every channel")
+	}
+
+	return nil
+}
+
+// Recipient identifies a user across the channels NotificationService can
+// dispatch to; leave fields empty for channels that don't apply.
+type Recipient struct {
+	Email          string
+	Phone          string
+	TelegramChatID string
+}
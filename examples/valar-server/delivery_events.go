@@ -0,0 +1,140 @@
+package grpcserver
+
+// DeliveryEventsServer implements email/v1's DeliveryEventsService: a
+// server-streaming RPC that reports what happened to a sent message after
+// SendEmail* returned, keyed by message_id. Events are produced by the
+// webhook handlers in webhook_ingest.go.
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	emailv1 "github.com/Cloud9Money/maia/proto/email/v1"
+)
+
+// DeliveryEventType enumerates the lifecycle transitions a sent message
+// can report.
+type DeliveryEventType string
+
+const (
+	EventQueued     DeliveryEventType = "queued"
+	EventSent       DeliveryEventType = "sent"
+	EventDelivered  DeliveryEventType = "delivered"
+	EventBounced    DeliveryEventType = "bounced"
+	EventComplained DeliveryEventType = "complained"
+	EventOpened     DeliveryEventType = "opened"
+)
+
+// DeliveryEvent is one lifecycle transition for a previously sent message.
+type DeliveryEvent struct {
+	MessageID string
+	Type      DeliveryEventType
+	Timestamp time.Time
+	Metadata  map[string]string
+}
+
+// EventStore durably records delivery events so StreamDeliveryEvents can
+// replay history to a newly connecting subscriber.
+type EventStore interface {
+	Append(ctx context.Context, event DeliveryEvent) error
+	List(ctx context.Context, messageID string) ([]DeliveryEvent, error)
+}
+
+// subscriber is one open StreamDeliveryEvents call.
+type subscriber struct {
+	messageID string // empty means "all messages"
+	ch        chan DeliveryEvent
+}
+
+// DeliveryEventsServer implements the DeliveryEventsService gRPC server.
+type DeliveryEventsServer struct {
+	emailv1.UnimplementedDeliveryEventsServiceServer
+
+	store  EventStore
+	logger Logger
+
+	mu          sync.Mutex
+	subscribers map[*subscriber]struct{}
+}
+
+// NewDeliveryEventsServer creates a DeliveryEventsServer backed by store.
+func NewDeliveryEventsServer(store EventStore, logger Logger) *DeliveryEventsServer {
+	return &DeliveryEventsServer{
+		store:       store,
+		logger:      logger,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Ingest persists event and fans it out to every matching subscriber. It's
+// called by the webhook handlers once a provider payload has been verified
+// and normalized.
+func (s *DeliveryEventsServer) Ingest(ctx context.Context, event DeliveryEvent) error {
+	if err := s.store.Append(ctx, event); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subscribers {
+		if sub.messageID != "" && sub.messageID != event.MessageID {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			s.logger.Error("delivery events: subscriber channel full, dropping event", "messageID", event.MessageID)
+		}
+	}
+	return nil
+}
+
+// StreamDeliveryEvents implements the StreamDeliveryEvents RPC: it first
+// replays stored history for the filter's message ID (if any), then
+// streams live events until the client disconnects.
+func (s *DeliveryEventsServer) StreamDeliveryEvents(req *emailv1.StreamDeliveryEventsRequest, stream emailv1.DeliveryEventsService_StreamDeliveryEventsServer) error {
+	ctx := stream.Context()
+
+	if req.MessageId != "" {
+		history, err := s.store.List(ctx, req.MessageId)
+		if err != nil {
+			return err
+		}
+		for _, event := range history {
+			if err := stream.Send(toProtoEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+
+	sub := &subscriber{messageID: req.MessageId, ch: make(chan DeliveryEvent, 16)}
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, sub)
+		s.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event := <-sub.ch:
+			if err := stream.Send(toProtoEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toProtoEvent(event DeliveryEvent) *emailv1.DeliveryEvent {
+	return &emailv1.DeliveryEvent{
+		MessageId: event.MessageID,
+		Type:      string(event.Type),
+		Timestamp: event.Timestamp.Unix(),
+		Metadata:  event.Metadata,
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	emailv1 "github.com/Cloud9Money/maia/proto/email/v1"
+	"github.com/Cloud9Money/valar/internal/outbox"
+	"github.com/Cloud9Money/valar/internal/templates"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -16,8 +18,37 @@ import (
 // EmailServer implements the EmailService gRPC server
 type EmailServer struct {
 	emailv1.UnimplementedEmailServiceServer
-	resendClient ResendClient // Your Resend email provider client
-	logger       Logger       // Your logger interface
+	providers     *ProviderChain      // Ordered chain of email backends with failover
+	outbox        *outbox.Outbox      // Durable, idempotent record of every send attempt
+	templates     *templates.Registry // Versioned, localized template registry
+	redirectHosts []string            // Allowlist for Options.redirect_to
+	userStatus    UserStatusChecker   // Optional; nil skips already-verified/disabled checks
+	logger        Logger              // Your logger interface
+}
+
+// UserStatusChecker lets EmailServer skip sending verification or reset
+// emails that shouldn't go out - the account is already verified, the
+// address belongs to a different account, or the account has been
+// disabled - rather than leaving that policy entirely to the caller. It's
+// optional: a nil checker on EmailServerConfig skips these checks.
+type UserStatusChecker interface {
+	IsVerified(ctx context.Context, email string) (bool, error)
+	// IsInUse reports whether email is already claimed by a different,
+	// already-registered account than the one requesting verification.
+	IsInUse(ctx context.Context, email string) (bool, error)
+	IsDisabled(ctx context.Context, email string) (bool, error)
+}
+
+// EmailServerConfig bundles EmailServer's policy knobs, mirroring the
+// xProviderConfig pattern used for the provider implementations.
+type EmailServerConfig struct {
+	// AllowedRedirectHosts is the server-side allowlist Options.redirect_to
+	// is validated against on SendVerificationEmail and
+	// SendPasswordResetEmail.
+	AllowedRedirectHosts []string
+	// UserStatus is consulted, if set, before sending a verification or
+	// reset email.
+	UserStatus UserStatusChecker
 }
 
 // ResendClient interface (implement this with actual Resend SDK)
@@ -32,11 +63,59 @@ type Logger interface {
 	Error(msg string, args ...interface{})
 }
 
-// NewEmailServer creates a new EmailServer instance
-func NewEmailServer(resendClient ResendClient, logger Logger) *EmailServer {
+// NewEmailServer creates a new EmailServer instance backed by the given
+// provider chain (see NewProviderChain), outbox (see outbox.New) and
+// template registry (see templates.NewRegistry). Every templated Send* RPC
+// is rendered through registry (SendEmail sends the caller's own subject
+// and body), and every Send* RPC is durably recorded in the outbox before
+// dispatch.
+func NewEmailServer(providers *ProviderChain, ob *outbox.Outbox, registry *templates.Registry, cfg EmailServerConfig, logger Logger) *EmailServer {
 	return &EmailServer{
-		resendClient: resendClient,
-		logger:       logger,
+		providers:     providers,
+		outbox:        ob,
+		templates:     registry,
+		redirectHosts: cfg.AllowedRedirectHosts,
+		userStatus:    cfg.UserStatus,
+		logger:        logger,
+	}
+}
+
+// sendViaChain durably records the email under idempotencyKey and makes
+// one synchronous dispatch attempt through the provider chain, mapping the
+// outcome onto the common SendEmailResponse shape. A retry with the same
+// idempotencyKey returns the previously stored result instead of sending
+// again; the outbox worker keeps retrying failures in the background.
+func (s *EmailServer) sendViaChain(ctx context.Context, idempotencyKey, addr, fromName, fromAddr string, email Email) *emailv1.SendEmailResponse {
+	result := s.outbox.SendEmail(ctx, &outbox.Message{
+		IdempotencyKey: idempotencyKey,
+		To:             addr,
+		FromName:       fromName,
+		FromAddr:       fromAddr,
+		Subject:        email.Subject,
+		HTMLBody:       email.HTMLBody,
+		TextBody:       email.TextBody,
+	})
+
+	if result.Err != nil {
+		s.logger.Error("failed to send email", "error", result.Err, "to", addr, "idempotencyKey", idempotencyKey)
+		return &emailv1.SendEmailResponse{
+			Success:   false,
+			Error:     result.Err.Error(),
+			Status:    string(result.Status),
+			Timestamp: time.Now().Unix(),
+		}
+	}
+
+	if result.Deduped {
+		s.logger.Info("idempotency key already processed, skipping resend", "idempotencyKey", idempotencyKey, "status", result.Status)
+	}
+
+	return &emailv1.SendEmailResponse{
+		MessageId: result.MessageID,
+		Provider:  result.Provider,
+		Success:   true,
+		Status:    string(result.Status),
+		Timestamp: time.Now().Unix(),
 	}
 }
 
@@ -54,27 +133,19 @@ func (s *EmailServer) SendEmail(ctx context.Context, req *emailv1.SendEmailReque
 	if req.HtmlBody == "" && req.TextBody == "" {
 		return nil, status.Error(codes.InvalidArgument, "email body is required")
 	}
-
-	// Send email via Resend
-	messageID, err := s.resendClient.SendEmail(req.To, req.Subject, req.HtmlBody, req.TextBody)
-	if err != nil {
-		s.logger.Error("Failed to send email", "error", err, "to", req.To)
-		return &emailv1.SendEmailResponse{
-			Success:   false,
-			Error:     err.Error(),
-			Status:    "failed",
-			Timestamp: time.Now().Unix(),
-		}, nil
+	if req.IdempotencyKey == "" {
+		return nil, status.Error(codes.InvalidArgument, "idempotency_key is required")
 	}
 
-	s.logger.Info("Email sent successfully", "messageID", messageID, "to", req.To)
-
-	return &emailv1.SendEmailResponse{
-		MessageId: messageID,
-		Success:   true,
-		Status:    "sent",
-		Timestamp: time.Now().Unix(),
-	}, nil
+	resp := s.sendViaChain(ctx, req.IdempotencyKey, req.To, "", "", Email{
+		Subject:  req.Subject,
+		HTMLBody: req.HtmlBody,
+		TextBody: req.TextBody,
+	})
+	if resp.Success {
+		s.logger.Info("Email sent successfully", "messageID", resp.MessageId, "provider", resp.Provider, "to", req.To)
+	}
+	return resp, nil
 }
 
 // SendVerificationEmail implements the SendVerificationEmail RPC
@@ -88,6 +159,40 @@ func (s *EmailServer) SendVerificationEmail(ctx context.Context, req *emailv1.Se
 	if req.VerificationToken == "" {
 		return nil, status.Error(codes.InvalidArgument, "verification token is required")
 	}
+	if req.IdempotencyKey == "" {
+		return nil, status.Error(codes.InvalidArgument, "idempotency_key is required")
+	}
+
+	if s.userStatus != nil {
+		inUse, err := s.userStatus.IsInUse(ctx, req.To)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "check email availability: %v", err)
+		}
+		if inUse {
+			return nil, errorWithDetail(codes.AlreadyExists, "email is already in use by another account", &emailv1.EmailAlreadyInUseDetail{})
+		}
+
+		verified, err := s.userStatus.IsVerified(ctx, req.To)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "check verification status: %v", err)
+		}
+		if verified {
+			return nil, errorWithDetail(codes.FailedPrecondition, "email is already verified", &emailv1.EmailAlreadyVerifiedDetail{})
+		}
+	}
+
+	locale := defaultLocale
+	if req.Options != nil && req.Options.Locale != "" {
+		locale = req.Options.Locale
+	}
+
+	var redirectTo string
+	if req.Options != nil {
+		redirectTo = req.Options.RedirectTo
+	}
+	if err := validateRedirect(s.redirectHosts, req.Options, redirectTo); err != nil {
+		return nil, err
+	}
 
 	// Build verification URL
 	verificationURL := req.VerificationUrl
@@ -95,36 +200,31 @@ func (s *EmailServer) SendVerificationEmail(ctx context.Context, req *emailv1.Se
 		// Use default verification URL if not provided
 		verificationURL = fmt.Sprintf("https://app.cloud9.money/verify?token=%s", req.VerificationToken)
 	}
+	verificationURL = withRedirect(verificationURL, redirectTo)
 
 	// Prepare template variables
 	variables := map[string]string{
-		"user_name":        req.UserName,
-		"verification_url": verificationURL,
+		"user_name":          req.UserName,
+		"verification_url":   verificationURL,
 		"verification_token": req.VerificationToken,
-		"app_name":         "Cloud9",
-		"support_email":    "support@cloud9.money",
+		"app_name":           "Cloud9",
+		"support_email":      "support@cloud9.money",
 	}
 
-	// Send email using verification template
-	messageID, err := s.resendClient.SendWithTemplate(req.To, "verification-email", variables)
+	rendered, err := s.templates.Render(ctx, TemplateVerification, locale, variables)
 	if err != nil {
-		s.logger.Error("Failed to send verification email", "error", err, "to", req.To)
-		return &emailv1.SendEmailResponse{
-			Success:   false,
-			Error:     err.Error(),
-			Status:    "failed",
-			Timestamp: time.Now().Unix(),
-		}, nil
+		return nil, mapRenderError(TemplateVerification, err)
 	}
 
-	s.logger.Info("Verification email sent successfully", "messageID", messageID, "to", req.To)
-
-	return &emailv1.SendEmailResponse{
-		MessageId: messageID,
-		Success:   true,
-		Status:    "sent",
-		Timestamp: time.Now().Unix(),
-	}, nil
+	resp := s.sendViaChain(ctx, req.IdempotencyKey, req.To, "", "", Email{
+		Subject:  rendered.Subject,
+		HTMLBody: rendered.HTML,
+		TextBody: rendered.Text,
+	})
+	if resp.Success {
+		s.logger.Info("Verification email sent successfully", "messageID", resp.MessageId, "provider", resp.Provider, "to", req.To)
+	}
+	return resp, nil
 }
 
 // SendPasswordResetEmail implements the SendPasswordResetEmail RPC
@@ -138,43 +238,64 @@ func (s *EmailServer) SendPasswordResetEmail(ctx context.Context, req *emailv1.S
 	if req.ResetToken == "" {
 		return nil, status.Error(codes.InvalidArgument, "reset token is required")
 	}
+	if req.IdempotencyKey == "" {
+		return nil, status.Error(codes.InvalidArgument, "idempotency_key is required")
+	}
+
+	if s.userStatus != nil {
+		disabled, err := s.userStatus.IsDisabled(ctx, req.To)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "check account status: %v", err)
+		}
+		if disabled {
+			return nil, errorWithDetail(codes.FailedPrecondition, "account is disabled", &emailv1.DisabledUserDetail{})
+		}
+	}
+
+	locale := defaultLocale
+	if req.Options != nil && req.Options.Locale != "" {
+		locale = req.Options.Locale
+	}
+
+	var redirectTo string
+	if req.Options != nil {
+		redirectTo = req.Options.RedirectTo
+	}
+	if err := validateRedirect(s.redirectHosts, req.Options, redirectTo); err != nil {
+		return nil, err
+	}
 
 	// Build reset URL
 	resetURL := req.ResetUrl
 	if resetURL == "" {
 		resetURL = fmt.Sprintf("https://app.cloud9.money/reset-password?token=%s", req.ResetToken)
 	}
+	resetURL = withRedirect(resetURL, redirectTo)
 
 	// Prepare template variables
 	variables := map[string]string{
-		"user_name":       req.UserName,
-		"reset_url":       resetURL,
-		"reset_token":     req.ResetToken,
-		"expiry_minutes":  fmt.Sprintf("%d", req.ExpiryMinutes),
-		"app_name":        "Cloud9",
-		"support_email":   "support@cloud9.money",
+		"user_name":      req.UserName,
+		"reset_url":      resetURL,
+		"reset_token":    req.ResetToken,
+		"expiry_minutes": fmt.Sprintf("%d", req.ExpiryMinutes),
+		"app_name":       "Cloud9",
+		"support_email":  "support@cloud9.money",
 	}
 
-	// Send email using password reset template
-	messageID, err := s.resendClient.SendWithTemplate(req.To, "password-reset", variables)
+	rendered, err := s.templates.Render(ctx, TemplatePasswordReset, locale, variables)
 	if err != nil {
-		s.logger.Error("Failed to send password reset email", "error", err, "to", req.To)
-		return &emailv1.SendEmailResponse{
-			Success:   false,
-			Error:     err.Error(),
-			Status:    "failed",
-			Timestamp: time.Now().Unix(),
-		}, nil
+		return nil, mapRenderError(TemplatePasswordReset, err)
 	}
 
-	s.logger.Info("Password reset email sent successfully", "messageID", messageID, "to", req.To)
-
-	return &emailv1.SendEmailResponse{
-		MessageId: messageID,
-		Success:   true,
-		Status:    "sent",
-		Timestamp: time.Now().Unix(),
-	}, nil
+	resp := s.sendViaChain(ctx, req.IdempotencyKey, req.To, "", "", Email{
+		Subject:  rendered.Subject,
+		HTMLBody: rendered.HTML,
+		TextBody: rendered.Text,
+	})
+	if resp.Success {
+		s.logger.Info("Password reset email sent successfully", "messageID", resp.MessageId, "provider", resp.Provider, "to", req.To)
+	}
+	return resp, nil
 }
 
 // SendWelcomeEmail implements the SendWelcomeEmail RPC
@@ -184,6 +305,9 @@ func (s *EmailServer) SendWelcomeEmail(ctx context.Context, req *emailv1.SendWel
 	if req.To == "" {
 		return nil, status.Error(codes.InvalidArgument, "recipient email is required")
 	}
+	if req.IdempotencyKey == "" {
+		return nil, status.Error(codes.InvalidArgument, "idempotency_key is required")
+	}
 
 	variables := map[string]string{
 		"user_name":     req.UserName,
@@ -192,23 +316,16 @@ func (s *EmailServer) SendWelcomeEmail(ctx context.Context, req *emailv1.SendWel
 		"dashboard_url": "https://app.cloud9.money/dashboard",
 	}
 
-	messageID, err := s.resendClient.SendWithTemplate(req.To, "welcome-email", variables)
+	rendered, err := s.templates.Render(ctx, TemplateWelcome, defaultLocale, variables)
 	if err != nil {
-		s.logger.Error("Failed to send welcome email", "error", err, "to", req.To)
-		return &emailv1.SendEmailResponse{
-			Success:   false,
-			Error:     err.Error(),
-			Status:    "failed",
-			Timestamp: time.Now().Unix(),
-		}, nil
+		return nil, mapRenderError(TemplateWelcome, err)
 	}
 
-	return &emailv1.SendEmailResponse{
-		MessageId: messageID,
-		Success:   true,
-		Status:    "sent",
-		Timestamp: time.Now().Unix(),
-	}, nil
+	return s.sendViaChain(ctx, req.IdempotencyKey, req.To, "", "", Email{
+		Subject:  rendered.Subject,
+		HTMLBody: rendered.HTML,
+		TextBody: rendered.Text,
+	}), nil
 }
 
 // SendTransactionNotification implements the SendTransactionNotification RPC
@@ -218,6 +335,9 @@ func (s *EmailServer) SendTransactionNotification(ctx context.Context, req *emai
 	if req.To == "" {
 		return nil, status.Error(codes.InvalidArgument, "recipient email is required")
 	}
+	if req.IdempotencyKey == "" {
+		return nil, status.Error(codes.InvalidArgument, "idempotency_key is required")
+	}
 
 	variables := map[string]string{
 		"transaction_id":   req.TransactionId,
@@ -230,23 +350,16 @@ func (s *EmailServer) SendTransactionNotification(ctx context.Context, req *emai
 		"app_name":         "Cloud9",
 	}
 
-	messageID, err := s.resendClient.SendWithTemplate(req.To, "transaction-notification", variables)
+	rendered, err := s.templates.Render(ctx, TemplateTransactionNotification, defaultLocale, variables)
 	if err != nil {
-		s.logger.Error("Failed to send transaction notification", "error", err, "to", req.To)
-		return &emailv1.SendEmailResponse{
-			Success:   false,
-			Error:     err.Error(),
-			Status:    "failed",
-			Timestamp: time.Now().Unix(),
-		}, nil
+		return nil, mapRenderError(TemplateTransactionNotification, err)
 	}
 
-	return &emailv1.SendEmailResponse{
-		MessageId: messageID,
-		Success:   true,
-		Status:    "sent",
-		Timestamp: time.Now().Unix(),
-	}, nil
+	return s.sendViaChain(ctx, req.IdempotencyKey, req.To, "", "", Email{
+		Subject:  rendered.Subject,
+		HTMLBody: rendered.HTML,
+		TextBody: rendered.Text,
+	}), nil
 }
 
 // SendTemplateEmail implements the SendTemplateEmail RPC
@@ -259,22 +372,39 @@ func (s *EmailServer) SendTemplateEmail(ctx context.Context, req *emailv1.SendTe
 	if req.TemplateId == "" {
 		return nil, status.Error(codes.InvalidArgument, "template ID is required")
 	}
+	if req.IdempotencyKey == "" {
+		return nil, status.Error(codes.InvalidArgument, "idempotency_key is required")
+	}
 
-	messageID, err := s.resendClient.SendWithTemplate(req.To, req.TemplateId, req.Variables)
+	rendered, err := s.templates.Render(ctx, req.TemplateId, defaultLocale, req.Variables)
 	if err != nil {
-		s.logger.Error("Failed to send template email", "error", err, "to", req.To, "templateID", req.TemplateId)
-		return &emailv1.SendEmailResponse{
-			Success:   false,
-			Error:     err.Error(),
-			Status:    "failed",
-			Timestamp: time.Now().Unix(),
-		}, nil
+		return nil, mapRenderError(req.TemplateId, err)
 	}
 
-	return &emailv1.SendEmailResponse{
-		MessageId: messageID,
-		Success:   true,
-		Status:    "sent",
-		Timestamp: time.Now().Unix(),
+	return s.sendViaChain(ctx, req.IdempotencyKey, req.To, "", "", Email{
+		Subject:  rendered.Subject,
+		HTMLBody: rendered.HTML,
+		TextBody: rendered.Text,
+	}), nil
+}
+
+// GetEmailStatus implements the GetEmailStatus RPC, letting callers poll
+// the outbox for the outcome of a previously submitted idempotency key -
+// used by EmailClient.WaitForDelivery.
+func (s *EmailServer) GetEmailStatus(ctx context.Context, req *emailv1.GetEmailStatusRequest) (*emailv1.GetEmailStatusResponse, error) {
+	if req.IdempotencyKey == "" {
+		return nil, status.Error(codes.InvalidArgument, "idempotency_key is required")
+	}
+
+	msg, err := s.outbox.Status(ctx, req.IdempotencyKey)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "no email found for idempotency_key %q", req.IdempotencyKey)
+	}
+
+	return &emailv1.GetEmailStatusResponse{
+		Status:    string(msg.Status),
+		MessageId: msg.MessageID,
+		Provider:  msg.Provider,
+		Error:     msg.LastError,
 	}, nil
 }
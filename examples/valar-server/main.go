@@ -12,7 +12,9 @@ import (
 	"syscall"
 
 	emailv1 "github.com/Cloud9Money/maia/proto/email/v1"
+	notificationv1 "github.com/Cloud9Money/maia/proto/notification/v1"
 	smsv1 "github.com/Cloud9Money/maia/proto/sms/v1"
+	templatev1 "github.com/Cloud9Money/maia/proto/template/v1"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
@@ -37,17 +39,73 @@ func main() {
 	)
 
 	// TODO: Initialize your dependencies
-	// resendClient := initializeResendClient()
 	// logger := initializeLogger()
 
-	// Register Email Service
-	// emailServer := grpcserver.NewEmailServer(resendClient, logger)
+	// Load the template registry: FSStore is handy for local dev and
+	// static deployments; swap in templates.NewPostgresStore(db) once
+	// templates need to be editable without a redeploy.
+	// templateStore, err := templates.NewFSStore(getEnv("TEMPLATE_DIR", "./templates"))
+	// templateRegistry := templates.NewRegistry(templateStore)
+
+	// Build the provider chain from config, in priority order. Each
+	// provider gets its own timeout, from-name and from-addr (ResendProvider
+	// does not pass the sender on; see ResendProvider.Send); dryRun
+	// renders and validates without dispatching (useful in staging).
+	// resendProvider := grpcserver.NewResendProvider(resendClient, grpcserver.ResendProviderConfig{
+	// 	FromName: getEnv("RESEND_FROM_NAME", "Cloud9"),
+	// 	FromAddr: getEnv("RESEND_FROM_ADDR", "no-reply@cloud9.money"),
+	// 	Timeout:  10 * time.Second,
+	// })
+	// smtpProvider := grpcserver.NewSMTPProvider(grpcserver.SMTPProviderConfig{
+	// 	Host:     getEnv("SMTP_HOST", ""),
+	// 	Security: grpcserver.SMTPSecurityStartTLS,
+	// 	FromName: getEnv("SMTP_FROM_NAME", "Cloud9"),
+	// 	FromAddr: getEnv("SMTP_FROM_ADDR", "no-reply@cloud9.money"),
+	// 	Timeout:  10 * time.Second,
+	// })
+	// dryRun := getEnv("EMAIL_DRY_RUN", "false") == "true"
+	// providerChain := grpcserver.NewProviderChain(logger, dryRun, resendProvider, smtpProvider)
+
+	// Wire the outbox: every Send* RPC is durably recorded here before
+	// dispatch, deduped by idempotency_key, with a background worker
+	// retrying failures on the backoff schedule.
+	// db, err := sql.Open("postgres", getEnv("OUTBOX_DSN", ""))
+	// outboxStore := outbox.NewPostgresStore(db, 24*time.Hour)
+	// emailOutbox := outbox.New(outboxStore, providerChain)
+	// outboxWorker := outbox.NewWorker(outboxStore, providerChain, logger, 10*time.Second, 50)
+	// go outboxWorker.Run(context.Background())
+
+	// Register Email Service. AllowedRedirectHosts guards the redirect_to
+	// Hama can ask SendVerificationEmail/SendPasswordResetEmail to embed
+	// in the link they send, so it can never point off our own domains.
+	// emailServer := grpcserver.NewEmailServer(providerChain, emailOutbox, templateRegistry, grpcserver.EmailServerConfig{
+	// 	AllowedRedirectHosts: strings.Split(getEnv("EMAIL_ALLOWED_REDIRECT_HOSTS", "app.cloud9.money"), ","),
+	// 	UserStatus:           userStatusChecker,
+	// }, logger)
 	// emailv1.RegisterEmailServiceServer(grpcServer, emailServer)
 
+	// Register Template Service: lets an admin UI register new template
+	// versions and preview them before they go live.
+	// templateServer := grpcserver.NewTemplateServer(templateRegistry, logger)
+	// templatev1.RegisterTemplateServiceServer(grpcServer, templateServer)
+
 	// Register SMS Service
 	// smsServer := grpcserver.NewSMSServer(smsProvider, logger)
 	// smsv1.RegisterSMSServiceServer(grpcServer, smsServer)
 
+	// Register Notification Service: fans a single template-driven call
+	// out to email, SMS and Telegram per the caller's channel preference
+	// (or "all" for critical alerts like password reset).
+	// smsSender := grpcserver.NewSMSClientAdapter(smsv1.NewSMSServiceClient(smsConn))
+	// telegramProvider := grpcserver.NewTelegramProvider(getEnv("TELEGRAM_BOT_TOKEN", ""))
+	// notificationServer := grpcserver.NewNotificationServer(emailServer, smsSender, telegramProvider, prefsStore, logger)
+	// notificationv1.RegisterNotificationServiceServer(grpcServer, notificationServer)
+
+	// Register Delivery Events Service
+	// eventStore := grpcserver.NewPostgresEventStore(db)
+	// deliveryEventsServer := grpcserver.NewDeliveryEventsServer(eventStore, logger)
+	// emailv1.RegisterDeliveryEventsServiceServer(grpcServer, deliveryEventsServer)
+
 	// Enable gRPC reflection for debugging with grpcurl
 	reflection.Register(grpcServer)
 
@@ -59,7 +117,21 @@ func main() {
 		}
 	}()
 
-	// TODO: Start HTTP server for health checks and metrics on httpPort
+	// Start HTTP server for health checks, metrics, and provider delivery
+	// webhooks (Resend/Mailgun/SES all POST delivery status here).
+	// webhookHandler := grpcserver.NewWebhookHandler(deliveryEventsServer, getEnv("RESEND_WEBHOOK_SECRET", ""), getEnv("MAILGUN_WEBHOOK_SIGNING_KEY", ""), logger)
+	// mux := http.NewServeMux()
+	// mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	// mux.HandleFunc("/webhooks/resend", webhookHandler.HandleResend)
+	// mux.HandleFunc("/webhooks/mailgun", webhookHandler.HandleMailgun)
+	// mux.HandleFunc("/webhooks/ses", webhookHandler.HandleSES)
+	// httpServer := &http.Server{Addr: fmt.Sprintf(":%s", httpPort), Handler: mux}
+	// go func() {
+	// 	log.Printf("HTTP server (webhooks, health) listening on :%s", httpPort)
+	// 	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	// 		log.Fatalf("Failed to serve HTTP: %v", err)
+	// 	}
+	// }()
 
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
@@ -0,0 +1,208 @@
+package grpcserver
+
+// NotificationServer implements the notification/v1 NotificationService,
+// fanning a single template-driven notification out to email, SMS and
+// Telegram depending on the caller's channel preference (or the user's
+// stored defaults).
+
+import (
+	"context"
+	"fmt"
+
+	notificationv1 "github.com/Cloud9Money/maia/proto/notification/v1"
+	"github.com/Cloud9Money/valar/internal/templates"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Channel names accepted in SendNotificationRequest.channels.
+const (
+	ChannelEmail    = "email"
+	ChannelSMS      = "sms"
+	ChannelTelegram = "telegram"
+)
+
+// Notification dispatch modes.
+const (
+	// ModeFallback tries channels in order and stops at the first success.
+	ModeFallback = "fallback"
+	// ModeAll dispatches to every requested channel, for critical alerts
+	// (e.g. password reset) where the user should be reachable any way possible.
+	ModeAll = "all"
+)
+
+// SMSSender is the minimal interface NotificationServer needs from the SMS
+// backend (implemented once the smsv1 server lands; see main.go).
+type SMSSender interface {
+	SendSMS(phone, message string) (messageID string, err error)
+}
+
+// PreferenceStore resolves a user's default channel order when the caller
+// doesn't specify one explicitly.
+type PreferenceStore interface {
+	ResolveChannels(ctx context.Context, email, phone, telegramChatID string) (channels []string, err error)
+}
+
+// NotificationServer implements the NotificationService gRPC server.
+type NotificationServer struct {
+	notificationv1.UnimplementedNotificationServiceServer
+	email    *EmailServer
+	sms      SMSSender
+	telegram *TelegramProvider
+	prefs    PreferenceStore
+	logger   Logger
+}
+
+// NewNotificationServer creates a NotificationServer that fans out
+// through the given per-channel backends.
+func NewNotificationServer(email *EmailServer, sms SMSSender, telegram *TelegramProvider, prefs PreferenceStore, logger Logger) *NotificationServer {
+	return &NotificationServer{
+		email:    email,
+		sms:      sms,
+		telegram: telegram,
+		prefs:    prefs,
+		logger:   logger,
+	}
+}
+
+// channelResult records the outcome of one channel attempt.
+type channelResult struct {
+	channel   string
+	success   bool
+	messageID string
+	err       error
+}
+
+// SendNotification implements the SendNotification RPC.
+func (s *NotificationServer) SendNotification(ctx context.Context, req *notificationv1.SendNotificationRequest) (*notificationv1.SendNotificationResponse, error) {
+	s.logger.Info("Received SendNotification request", "templateKey", req.TemplateKey, "mode", req.Mode)
+
+	if req.TemplateKey == "" {
+		return nil, status.Error(codes.InvalidArgument, "template_key is required")
+	}
+	if req.Email == "" && req.Phone == "" && req.TelegramChatId == "" {
+		return nil, status.Error(codes.InvalidArgument, "at least one of email, phone, or telegram_chat_id is required")
+	}
+
+	channels := req.Channels
+	if len(channels) == 0 {
+		resolved, err := s.prefs.ResolveChannels(ctx, req.Email, req.Phone, req.TelegramChatId)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "resolve channel preferences: %v", err)
+		}
+		channels = resolved
+	}
+
+	mode := req.Mode
+	if mode == "" {
+		mode = ModeFallback
+	}
+
+	var results []channelResult
+	for _, channel := range channels {
+		result := s.dispatch(ctx, channel, req)
+		results = append(results, result)
+
+		if mode == ModeFallback && result.success {
+			break
+		}
+	}
+
+	return buildNotificationResponse(results), nil
+}
+
+// render resolves req's template against the shared registry, so every
+// channel renders off the same registered template instead of each
+// picking its own placeholder content.
+func (s *NotificationServer) render(ctx context.Context, req *notificationv1.SendNotificationRequest) (*templates.Rendered, error) {
+	return s.email.templates.Render(ctx, req.TemplateKey, defaultLocale, req.Variables)
+}
+
+// dispatch sends req's rendered template through a single channel.
+func (s *NotificationServer) dispatch(ctx context.Context, channel string, req *notificationv1.SendNotificationRequest) channelResult {
+	switch channel {
+	case ChannelEmail:
+		return s.dispatchEmail(ctx, req)
+	case ChannelSMS:
+		return s.dispatchSMS(ctx, req)
+	case ChannelTelegram:
+		return s.dispatchTelegram(ctx, req)
+	default:
+		return channelResult{channel: channel, err: fmt.Errorf("unknown channel %q", channel)}
+	}
+}
+
+func (s *NotificationServer) dispatchEmail(ctx context.Context, req *notificationv1.SendNotificationRequest) channelResult {
+	if req.Email == "" {
+		return channelResult{channel: ChannelEmail, err: fmt.Errorf("no email address provided")}
+	}
+
+	rendered, err := s.render(ctx, req)
+	if err != nil {
+		return channelResult{channel: ChannelEmail, err: err}
+	}
+
+	email := Email{Subject: rendered.Subject, HTMLBody: rendered.HTML, TextBody: rendered.Text}
+	resp := s.email.sendViaChain(ctx, req.IdempotencyKey, req.Email, "", "", email)
+	if !resp.Success {
+		return channelResult{channel: ChannelEmail, err: fmt.Errorf("%s", resp.Error)}
+	}
+	return channelResult{channel: ChannelEmail, success: true, messageID: resp.MessageId}
+}
+
+func (s *NotificationServer) dispatchSMS(ctx context.Context, req *notificationv1.SendNotificationRequest) channelResult {
+	if s.sms == nil {
+		return channelResult{channel: ChannelSMS, err: fmt.Errorf("sms channel is not configured")}
+	}
+	if req.Phone == "" {
+		return channelResult{channel: ChannelSMS, err: fmt.Errorf("no phone number provided")}
+	}
+
+	rendered, err := s.render(ctx, req)
+	if err != nil {
+		return channelResult{channel: ChannelSMS, err: err}
+	}
+
+	messageID, err := s.sms.SendSMS(req.Phone, rendered.Text)
+	if err != nil {
+		return channelResult{channel: ChannelSMS, err: err}
+	}
+	return channelResult{channel: ChannelSMS, success: true, messageID: messageID}
+}
+
+func (s *NotificationServer) dispatchTelegram(ctx context.Context, req *notificationv1.SendNotificationRequest) channelResult {
+	if s.telegram == nil {
+		return channelResult{channel: ChannelTelegram, err: fmt.Errorf("telegram channel is not configured")}
+	}
+	if req.TelegramChatId == "" {
+		return channelResult{channel: ChannelTelegram, err: fmt.Errorf("no telegram_chat_id provided")}
+	}
+
+	rendered, err := s.render(ctx, req)
+	if err != nil {
+		return channelResult{channel: ChannelTelegram, err: err}
+	}
+
+	messageID, err := s.telegram.SendMessage(req.TelegramChatId, rendered.Text)
+	if err != nil {
+		return channelResult{channel: ChannelTelegram, err: err}
+	}
+	return channelResult{channel: ChannelTelegram, success: true, messageID: messageID}
+}
+
+func buildNotificationResponse(results []channelResult) *notificationv1.SendNotificationResponse {
+	resp := &notificationv1.SendNotificationResponse{}
+	for _, r := range results {
+		channelResp := &notificationv1.ChannelResult{
+			Channel:   r.channel,
+			Success:   r.success,
+			MessageId: r.messageID,
+		}
+		if r.err != nil {
+			channelResp.Error = r.err.Error()
+		}
+		resp.ChannelResults = append(resp.ChannelResults, channelResp)
+		resp.Success = resp.Success || r.success
+	}
+	return resp
+}
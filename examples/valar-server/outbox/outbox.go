@@ -0,0 +1,94 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Sender is the minimal interface the outbox needs to actually dispatch a
+// message. grpcserver.ProviderChain satisfies this directly.
+type Sender interface {
+	Send(addr, fromName, fromAddr, subject, htmlBody, textBody string) (messageID, provider string, err error)
+}
+
+// Outbox durably records every send before attempting dispatch and
+// deduplicates retries that share an idempotency key.
+type Outbox struct {
+	store  Store
+	sender Sender
+}
+
+// New creates an Outbox backed by store, dispatching through sender.
+func New(store Store, sender Sender) *Outbox {
+	return &Outbox{store: store, sender: sender}
+}
+
+// Result is what SendEmail reports back to the caller.
+type Result struct {
+	MessageID string
+	Provider  string
+	Status    Status
+	Deduped   bool // true if this idempotency key already had a durable row
+	Err       error
+}
+
+// SendEmail writes msg to the store (or returns the existing row if
+// idempotencyKey was already seen within its TTL), then makes one
+// synchronous dispatch attempt. On failure the row is left for the
+// background Worker to retry with backoff.
+func (o *Outbox) SendEmail(ctx context.Context, msg *Message) Result {
+	if existing, err := o.store.Get(ctx, msg.IdempotencyKey); err == nil {
+		return dedupedResult(msg.IdempotencyKey, existing)
+	}
+
+	if err := o.store.Insert(ctx, msg); err != nil {
+		if errors.Is(err, ErrConflict) {
+			// Another caller won the race to insert this idempotency
+			// key between our Get above and our Insert; their row is
+			// now durable, so defer to it instead of dispatching a
+			// second copy against the ID we generated but never persisted.
+			existing, getErr := o.store.Get(ctx, msg.IdempotencyKey)
+			if getErr != nil {
+				return Result{Err: getErr}
+			}
+			return dedupedResult(msg.IdempotencyKey, existing)
+		}
+		return Result{Err: err}
+	}
+	if err := o.store.MarkSending(ctx, msg.ID); err != nil {
+		return Result{Err: err}
+	}
+
+	messageID, provider, err := o.sender.Send(msg.To, msg.FromName, msg.FromAddr, msg.Subject, msg.HTMLBody, msg.TextBody)
+	if err != nil {
+		_ = o.store.MarkFailed(ctx, msg.ID, err, nextAttemptTime(msg.Attempts))
+		return Result{Status: StatusFailed, Err: err}
+	}
+
+	if err := o.store.MarkSent(ctx, msg.ID, messageID, provider); err != nil {
+		return Result{Err: err}
+	}
+	return Result{MessageID: messageID, Provider: provider, Status: StatusSent}
+}
+
+// dedupedResult turns an already-durable row into the Result SendEmail
+// reports back for a retried idempotency key, instead of dispatching again.
+func dedupedResult(idempotencyKey string, existing *Message) Result {
+	switch existing.Status {
+	case StatusSent:
+		return Result{MessageID: existing.MessageID, Provider: existing.Provider, Status: existing.Status, Deduped: true}
+	case StatusDeadLetter:
+		return Result{Status: existing.Status, Deduped: true, Err: fmt.Errorf("outbox: message %s previously dead-lettered: %s", idempotencyKey, existing.LastError)}
+	default:
+		// pending/sending/failed: a dispatch attempt is already in
+		// flight or scheduled; don't send a second copy.
+		return Result{Status: existing.Status, Deduped: true}
+	}
+}
+
+// Status looks up the current delivery status for an idempotency key,
+// used by EmailServer.GetEmailStatus.
+func (o *Outbox) Status(ctx context.Context, idempotencyKey string) (*Message, error) {
+	return o.store.Get(ctx, idempotencyKey)
+}
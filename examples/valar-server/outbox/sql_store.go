@@ -0,0 +1,195 @@
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// newID generates a client-side row ID so callers (MarkSending, MarkSent,
+// MarkFailed) have something to key their UPDATE on without relying on a
+// dialect-specific way to read back an auto-increment/RETURNING value.
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// dialect captures the small SQL differences between Postgres and SQLite
+// that sqlStore needs to account for (placeholder syntax, upsert clause).
+type dialect struct {
+	name        string
+	placeholder func(n int) string
+	upsert      string
+}
+
+var postgresDialect = dialect{
+	name:        "postgres",
+	placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+	upsert:      "ON CONFLICT (idempotency_key) DO NOTHING",
+}
+
+var sqliteDialect = dialect{
+	name:        "sqlite",
+	placeholder: func(n int) string { return "?" },
+	upsert:      "ON CONFLICT(idempotency_key) DO NOTHING",
+}
+
+// sqlStore is a database/sql-backed Store shared by the Postgres and
+// SQLite flavors; only the dialect differs between them.
+type sqlStore struct {
+	db      *sql.DB
+	dialect dialect
+	ttl     time.Duration
+}
+
+// PostgresStore is a Store backed by a Postgres `email_outbox` table.
+type PostgresStore struct{ *sqlStore }
+
+// NewPostgresStore wraps an existing *sql.DB. ttl controls how long an
+// idempotency key is honored for deduplication.
+func NewPostgresStore(db *sql.DB, ttl time.Duration) *PostgresStore {
+	return &PostgresStore{&sqlStore{db: db, dialect: postgresDialect, ttl: ttl}}
+}
+
+// SQLiteStore is a Store backed by a SQLite `email_outbox` table.
+type SQLiteStore struct{ *sqlStore }
+
+// NewSQLiteStore wraps an existing *sql.DB. ttl controls how long an
+// idempotency key is honored for deduplication.
+func NewSQLiteStore(db *sql.DB, ttl time.Duration) *SQLiteStore {
+	return &SQLiteStore{&sqlStore{db: db, dialect: sqliteDialect, ttl: ttl}}
+}
+
+func (s *sqlStore) Get(ctx context.Context, idempotencyKey string) (*Message, error) {
+	query := fmt.Sprintf(`
+		SELECT id, idempotency_key, to_addr, from_name, from_addr, subject, html_body, text_body,
+		       status, attempts, last_error, message_id, provider, next_attempt, expires_at, created_at, updated_at
+		FROM email_outbox WHERE idempotency_key = %s AND expires_at > %s`,
+		s.dialect.placeholder(1), s.dialect.placeholder(2))
+
+	row := s.db.QueryRowContext(ctx, query, idempotencyKey, time.Now())
+
+	var m Message
+	var lastError, messageID, provider sql.NullString
+	err := row.Scan(&m.ID, &m.IdempotencyKey, &m.To, &m.FromName, &m.FromAddr, &m.Subject, &m.HTMLBody, &m.TextBody,
+		&m.Status, &m.Attempts, &lastError, &messageID, &provider, &m.NextAttempt, &m.ExpiresAt, &m.CreatedAt, &m.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("outbox: get %s: %w", idempotencyKey, err)
+	}
+	m.LastError, m.MessageID, m.Provider = lastError.String, messageID.String, provider.String
+	return &m, nil
+}
+
+func (s *sqlStore) Insert(ctx context.Context, msg *Message) error {
+	now := time.Now()
+	msg.ID = newID()
+	msg.Status = StatusPending
+	msg.CreatedAt, msg.UpdatedAt = now, now
+	msg.ExpiresAt = now.Add(s.ttl)
+
+	query := fmt.Sprintf(`
+		INSERT INTO email_outbox (id, idempotency_key, to_addr, from_name, from_addr, subject, html_body, text_body,
+		                           status, attempts, next_attempt, expires_at, created_at, updated_at)
+		VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s, 0, %s, %s, %s, %s) %s`,
+		s.dialect.placeholder(1), s.dialect.placeholder(2), s.dialect.placeholder(3), s.dialect.placeholder(4),
+		s.dialect.placeholder(5), s.dialect.placeholder(6), s.dialect.placeholder(7), s.dialect.placeholder(8),
+		s.dialect.placeholder(9), s.dialect.placeholder(10), s.dialect.placeholder(11), s.dialect.placeholder(12),
+		s.dialect.placeholder(13), s.dialect.upsert)
+
+	res, err := s.db.ExecContext(ctx, query, msg.ID, msg.IdempotencyKey, msg.To, msg.FromName, msg.FromAddr, msg.Subject,
+		msg.HTMLBody, msg.TextBody, msg.Status, now, msg.ExpiresAt, now, now)
+	if err != nil {
+		return fmt.Errorf("outbox: insert %s: %w", msg.IdempotencyKey, err)
+	}
+
+	// ON CONFLICT ... DO NOTHING makes ExecContext succeed even when the
+	// row already existed and nothing was written; msg.ID above was never
+	// persisted in that case, so callers must not act on it.
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("outbox: insert %s: rows affected: %w", msg.IdempotencyKey, err)
+	}
+	if affected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (s *sqlStore) MarkSending(ctx context.Context, id string) error {
+	return s.setStatus(ctx, id, StatusSending, nil, "", "", time.Time{})
+}
+
+func (s *sqlStore) MarkSent(ctx context.Context, id, messageID, provider string) error {
+	return s.setStatus(ctx, id, StatusSent, nil, messageID, provider, time.Time{})
+}
+
+func (s *sqlStore) MarkFailed(ctx context.Context, id string, sendErr error, nextAttempt time.Time) error {
+	status := StatusFailed
+	if nextAttempt.IsZero() {
+		status = StatusDeadLetter
+	}
+	return s.setStatus(ctx, id, status, sendErr, "", "", nextAttempt)
+}
+
+func (s *sqlStore) setStatus(ctx context.Context, id string, status Status, sendErr error, messageID, provider string, nextAttempt time.Time) error {
+	errMsg := ""
+	if sendErr != nil {
+		errMsg = sendErr.Error()
+	}
+	// Only the move to sending counts as a dispatch attempt; the sent or
+	// failed update that follows records the outcome of that same attempt.
+	attempts := "attempts"
+	if status == StatusSending {
+		attempts = "attempts + 1"
+	}
+	query := fmt.Sprintf(`
+		UPDATE email_outbox
+		SET status = %s, attempts = %s, last_error = %s, message_id = %s, provider = %s,
+		    next_attempt = %s, updated_at = %s
+		WHERE id = %s`,
+		s.dialect.placeholder(1), attempts, s.dialect.placeholder(2), s.dialect.placeholder(3), s.dialect.placeholder(4),
+		s.dialect.placeholder(5), s.dialect.placeholder(6), s.dialect.placeholder(7))
+
+	_, err := s.db.ExecContext(ctx, query, status, errMsg, messageID, provider, nextAttempt, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("outbox: update %s: %w", id, err)
+	}
+	return nil
+}
+
+func (s *sqlStore) ClaimPending(ctx context.Context, limit int) ([]*Message, error) {
+	query := fmt.Sprintf(`
+		SELECT id, idempotency_key, to_addr, from_name, from_addr, subject, html_body, text_body,
+		       status, attempts, last_error, message_id, provider, next_attempt, expires_at, created_at, updated_at
+		FROM email_outbox
+		WHERE status IN ('pending', 'failed') AND next_attempt <= %s
+		ORDER BY next_attempt ASC
+		LIMIT %s`,
+		s.dialect.placeholder(1), s.dialect.placeholder(2))
+
+	rows, err := s.db.QueryContext(ctx, query, time.Now(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("outbox: claim pending: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*Message
+	for rows.Next() {
+		var m Message
+		var lastError, messageID, provider sql.NullString
+		if err := rows.Scan(&m.ID, &m.IdempotencyKey, &m.To, &m.FromName, &m.FromAddr, &m.Subject, &m.HTMLBody, &m.TextBody,
+			&m.Status, &m.Attempts, &lastError, &messageID, &provider, &m.NextAttempt, &m.ExpiresAt, &m.CreatedAt, &m.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("outbox: scan pending row: %w", err)
+		}
+		m.LastError, m.MessageID, m.Provider = lastError.String, messageID.String, provider.String
+		out = append(out, &m)
+	}
+	return out, rows.Err()
+}
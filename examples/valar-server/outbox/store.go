@@ -0,0 +1,80 @@
+// Package outbox implements the transactional outbox pattern for Valar's
+// EmailServer: every send is durably recorded before dispatch, keyed by a
+// caller-supplied idempotency key, so retries from Hama never result in a
+// duplicate email.
+package outbox
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Status is the lifecycle state of an outbox row.
+type Status string
+
+const (
+	StatusPending    Status = "pending"
+	StatusSending    Status = "sending"
+	StatusSent       Status = "sent"
+	StatusFailed     Status = "failed"
+	StatusDeadLetter Status = "dead_letter"
+)
+
+// ErrNotFound is returned by Store.Get when no row matches the idempotency key.
+var ErrNotFound = errors.New("outbox: message not found")
+
+// ErrConflict is returned by Store.Insert when another caller concurrently
+// won the race to insert the same idempotency key: the row msg describes
+// was never persisted and the caller should Get the existing row instead.
+var ErrConflict = errors.New("outbox: idempotency key already inserted by a concurrent caller")
+
+// Message is a single outbound email tracked by the outbox.
+type Message struct {
+	ID             string
+	IdempotencyKey string
+	To             string
+	FromName       string
+	FromAddr       string
+	Subject        string
+	HTMLBody       string
+	TextBody       string
+
+	Status      Status
+	Attempts    int
+	LastError   string
+	MessageID   string // provider-assigned message ID once sent
+	Provider    string
+	NextAttempt time.Time
+	ExpiresAt   time.Time // dedup TTL: rows older than this are no longer returned by Get
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Store is the durable backing store for the outbox. Postgres and SQLite
+// implementations are provided in sql_store.go; both share the same
+// database/sql-based logic behind a dialect switch.
+type Store interface {
+	// Get returns the existing row for idempotencyKey, or ErrNotFound if
+	// none exists or it has expired past its dedup TTL.
+	Get(ctx context.Context, idempotencyKey string) (*Message, error)
+
+	// Insert durably persists a new pending row and assigns it an ID. If
+	// idempotencyKey was concurrently inserted by another caller first,
+	// Insert returns ErrConflict and msg.ID is not valid to act on.
+	Insert(ctx context.Context, msg *Message) error
+
+	// MarkSending transitions a row from pending/failed to sending.
+	MarkSending(ctx context.Context, id string) error
+
+	// MarkSent records a successful dispatch.
+	MarkSent(ctx context.Context, id, messageID, provider string) error
+
+	// MarkFailed records a failed attempt and schedules the next retry.
+	// If nextAttempt is zero, the row is moved to dead_letter instead.
+	MarkFailed(ctx context.Context, id string, sendErr error, nextAttempt time.Time) error
+
+	// ClaimPending returns up to limit rows that are due for (re)dispatch,
+	// i.e. pending or failed with NextAttempt <= now.
+	ClaimPending(ctx context.Context, limit int) ([]*Message, error)
+}
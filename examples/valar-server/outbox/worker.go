@@ -0,0 +1,97 @@
+package outbox
+
+import (
+	"context"
+	"time"
+)
+
+// backoffSchedule is the retry delay after each consecutive failure,
+// capped: a message that exhausts the schedule moves to dead_letter.
+var backoffSchedule = []time.Duration{
+	1 * time.Second,
+	5 * time.Second,
+	30 * time.Second,
+	5 * time.Minute,
+	30 * time.Minute,
+	6 * time.Hour,
+}
+
+// nextAttemptTime returns when a message should be retried after
+// `attempts` prior failures, or the zero time once the schedule is
+// exhausted (signalling dead_letter).
+func nextAttemptTime(attempts int) time.Time {
+	if attempts >= len(backoffSchedule) {
+		return time.Time{}
+	}
+	return time.Now().Add(backoffSchedule[attempts])
+}
+
+// Worker polls the store for pending/retryable rows and dispatches them
+// through the provider chain, applying the backoff schedule on failure.
+type Worker struct {
+	store  Store
+	sender Sender
+	logger interface {
+		Info(msg string, args ...interface{})
+		Error(msg string, args ...interface{})
+	}
+	pollEvery time.Duration
+	batchSize int
+}
+
+// NewWorker creates a Worker that polls the store every pollEvery,
+// dispatching up to batchSize due messages per poll.
+func NewWorker(store Store, sender Sender, logger interface {
+	Info(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}, pollEvery time.Duration, batchSize int) *Worker {
+	return &Worker{store: store, sender: sender, logger: logger, pollEvery: pollEvery, batchSize: batchSize}
+}
+
+// Run polls until ctx is canceled. Call it in a goroutine from main.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.pollEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.dispatchDue(ctx)
+		}
+	}
+}
+
+func (w *Worker) dispatchDue(ctx context.Context) {
+	due, err := w.store.ClaimPending(ctx, w.batchSize)
+	if err != nil {
+		w.logger.Error("outbox worker: failed to claim pending messages", "error", err)
+		return
+	}
+
+	for _, msg := range due {
+		if err := w.store.MarkSending(ctx, msg.ID); err != nil {
+			w.logger.Error("outbox worker: failed to mark sending", "id", msg.ID, "error", err)
+			continue
+		}
+
+		messageID, provider, err := w.sender.Send(msg.To, msg.FromName, msg.FromAddr, msg.Subject, msg.HTMLBody, msg.TextBody)
+		if err != nil {
+			next := nextAttemptTime(msg.Attempts)
+			if next.IsZero() {
+				w.logger.Error("outbox worker: message exhausted retries, dead-lettering", "id", msg.ID, "error", err)
+			} else {
+				w.logger.Error("outbox worker: retry failed, rescheduled", "id", msg.ID, "next_attempt", next, "error", err)
+			}
+			_ = w.store.MarkFailed(ctx, msg.ID, err, next)
+			continue
+		}
+
+		if err := w.store.MarkSent(ctx, msg.ID, messageID, provider); err != nil {
+			w.logger.Error("outbox worker: failed to mark sent", "id", msg.ID, "error", err)
+			continue
+		}
+		w.logger.Info("outbox worker: delivered", "id", msg.ID, "provider", provider, "messageID", messageID)
+	}
+}
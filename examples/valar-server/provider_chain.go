@@ -0,0 +1,121 @@
+package grpcserver
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxConsecutiveFailures is how many consecutive Send failures mark a
+// provider unhealthy and route traffic to the next one in the chain.
+const maxConsecutiveFailures = 3
+
+// unhealthyRetryAfter is how long an unhealthy provider is skipped before
+// the chain gives it another chance.
+const unhealthyRetryAfter = 2 * time.Minute
+
+// chainEntry pairs a provider with its circuit-breaker state.
+type chainEntry struct {
+	provider EmailProvider
+
+	mu                  sync.Mutex
+	consecutiveFailures int
+	unhealthyUntil      time.Time
+}
+
+func (e *chainEntry) healthy(now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return now.After(e.unhealthyUntil)
+}
+
+func (e *chainEntry) recordSuccess() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.consecutiveFailures = 0
+	e.unhealthyUntil = time.Time{}
+}
+
+func (e *chainEntry) recordFailure(now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.consecutiveFailures++
+	if e.consecutiveFailures >= maxConsecutiveFailures {
+		e.unhealthyUntil = now.Add(unhealthyRetryAfter)
+	}
+}
+
+// ProviderChain tries EmailProviders in priority order, skipping any that
+// have tripped their circuit breaker after maxConsecutiveFailures.
+type ProviderChain struct {
+	entries []*chainEntry
+	logger  Logger
+	dryRun  bool
+}
+
+// NewProviderChain builds a chain that tries providers in the given
+// priority order. When dryRun is true, Send validates the recipient and
+// renders nothing to the wire - see dryRunSend.
+func NewProviderChain(logger Logger, dryRun bool, providers ...EmailProvider) *ProviderChain {
+	entries := make([]*chainEntry, 0, len(providers))
+	for _, p := range providers {
+		entries = append(entries, &chainEntry{provider: p})
+	}
+	return &ProviderChain{entries: entries, logger: logger, dryRun: dryRun}
+}
+
+// Send adapts SendEmail to the primitive signature outbox.Sender expects,
+// so a *ProviderChain can be handed directly to outbox.New.
+func (c *ProviderChain) Send(addr, fromName, fromAddr, subject, htmlBody, textBody string) (messageID, provider string, err error) {
+	return c.SendEmail(addr, fromName, fromAddr, Email{Subject: subject, HTMLBody: htmlBody, TextBody: textBody})
+}
+
+// SendEmail dispatches email through the first healthy provider, falling
+// through to the next on failure. It returns the message ID and the name
+// of the provider that actually delivered the message.
+func (c *ProviderChain) SendEmail(addr, fromName, fromAddr string, email Email) (messageID, provider string, err error) {
+	if len(c.entries) == 0 {
+		return "", "", fmt.Errorf("provider chain: no providers configured")
+	}
+
+	if c.dryRun {
+		return c.dryRunSend(addr, email)
+	}
+
+	now := time.Now()
+	var lastErr error
+	for _, entry := range c.entries {
+		if !entry.healthy(now) {
+			c.logger.Info("skipping unhealthy provider", "provider", entry.provider.Name())
+			continue
+		}
+
+		messageID, err := entry.provider.Send(addr, fromName, fromAddr, email)
+		if err != nil {
+			entry.recordFailure(now)
+			c.logger.Error("provider send failed, trying next", "provider", entry.provider.Name(), "error", err)
+			lastErr = err
+			continue
+		}
+
+		entry.recordSuccess()
+		return messageID, entry.provider.Name(), nil
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("provider chain: all providers unhealthy")
+	}
+	return "", "", fmt.Errorf("provider chain exhausted: %w", lastErr)
+}
+
+// dryRunSend validates the recipient and renders nothing to the wire,
+// used to smoke-test templates without dispatching real email.
+func (c *ProviderChain) dryRunSend(addr string, email Email) (messageID, provider string, err error) {
+	if addr == "" {
+		return "", "", fmt.Errorf("dry run: recipient address is required")
+	}
+	if email.HTMLBody == "" && email.TextBody == "" {
+		return "", "", fmt.Errorf("dry run: rendered template produced an empty body")
+	}
+	return fmt.Sprintf("dry-run-%d", time.Now().UnixNano()), "dry-run", nil
+}
@@ -0,0 +1,347 @@
+package grpcserver
+
+// Email provider abstractions for Valar's EmailServer.
+// EmailProvider generalizes the old ResendClient so the server can fail
+// over between multiple backends (Resend, SMTP, Mailgun, ...).
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Email is the backend-agnostic payload handed to an EmailProvider.
+type Email struct {
+	Subject  string
+	HTMLBody string
+	TextBody string
+}
+
+// EmailProvider is implemented by each concrete email backend (Resend,
+// SMTP, Mailgun, ...). Send returns the provider-assigned message ID on
+// success.
+type EmailProvider interface {
+	// Name identifies the provider for logging and the SendEmailResponse.Provider field.
+	Name() string
+	Send(addr, fromName, fromAddr string, email Email) (messageID string, err error)
+}
+
+// ResendProviderConfig configures the Resend-backed provider.
+type ResendProviderConfig struct {
+	APIKey   string
+	FromName string
+	FromAddr string
+	Timeout  time.Duration
+}
+
+// ResendProvider sends email via the Resend API.
+type ResendProvider struct {
+	client   ResendClient
+	fromName string
+	fromAddr string
+	timeout  time.Duration
+}
+
+// NewResendProvider wraps an existing ResendClient as an EmailProvider.
+func NewResendProvider(client ResendClient, cfg ResendProviderConfig) *ResendProvider {
+	return &ResendProvider{
+		client:   client,
+		fromName: cfg.FromName,
+		fromAddr: cfg.FromAddr,
+		timeout:  cfg.Timeout,
+	}
+}
+
+func (p *ResendProvider) Name() string { return "resend" }
+
+// Send delivers email through Resend. ResendClient.SendEmail takes no
+// sender, so neither fromName/fromAddr nor the configured FromName/FromAddr
+// are passed on to Resend.
+func (p *ResendProvider) Send(addr, fromName, fromAddr string, email Email) (string, error) {
+	if fromName == "" {
+		fromName = p.fromName
+	}
+	if fromAddr == "" {
+		fromAddr = p.fromAddr
+	}
+	return callWithTimeout(p.timeout, func() (string, error) {
+		return p.client.SendEmail(addr, email.Subject, email.HTMLBody, email.TextBody)
+	})
+}
+
+// SMTPSecurity selects how the SMTPProvider establishes transport security.
+type SMTPSecurity int
+
+const (
+	// SMTPSecuritySSL dials directly over TLS (implicit TLS, typically port 465).
+	SMTPSecuritySSL SMTPSecurity = iota
+	// SMTPSecurityStartTLS dials in plaintext then upgrades via STARTTLS (typically port 587).
+	SMTPSecurityStartTLS
+)
+
+// SMTPProviderConfig configures the SMTP-backed provider.
+type SMTPProviderConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Security SMTPSecurity
+	FromName string
+	FromAddr string
+	Timeout  time.Duration
+}
+
+// SMTPProvider sends email over SMTP, supporting both implicit TLS (SSL)
+// and STARTTLS.
+type SMTPProvider struct {
+	cfg SMTPProviderConfig
+}
+
+// NewSMTPProvider creates a provider that dials cfg.Host:cfg.Port using
+// the configured security mode.
+func NewSMTPProvider(cfg SMTPProviderConfig) *SMTPProvider {
+	return &SMTPProvider{cfg: cfg}
+}
+
+func (p *SMTPProvider) Name() string { return "smtp" }
+
+func (p *SMTPProvider) Send(addr, fromName, fromAddr string, email Email) (string, error) {
+	if fromName == "" {
+		fromName = p.cfg.FromName
+	}
+	if fromAddr == "" {
+		fromAddr = p.cfg.FromAddr
+	}
+
+	hostPort := fmt.Sprintf("%s:%d", p.cfg.Host, p.cfg.Port)
+	auth := smtp.PlainAuth("", p.cfg.Username, p.cfg.Password, p.cfg.Host)
+	msg := buildRFC822Message(fromName, fromAddr, addr, email)
+
+	switch p.cfg.Security {
+	case SMTPSecuritySSL:
+		if err := p.sendSSL(hostPort, auth, fromAddr, addr, msg); err != nil {
+			return "", err
+		}
+	default:
+		if err := p.sendStartTLS(hostPort, auth, fromAddr, addr, msg); err != nil {
+			return "", err
+		}
+	}
+
+	// Plain SMTP has no concept of a provider message ID; synthesize one
+	// so downstream idempotency/delivery tracking has something stable.
+	return fmt.Sprintf("smtp-%d", time.Now().UnixNano()), nil
+}
+
+// dialTimeout is cfg.Timeout, or a sane default if unset.
+func (p *SMTPProvider) dialTimeout() time.Duration {
+	if p.cfg.Timeout > 0 {
+		return p.cfg.Timeout
+	}
+	return 10 * time.Second
+}
+
+func (p *SMTPProvider) sendSSL(hostPort string, auth smtp.Auth, from, to string, msg []byte) error {
+	dialer := &net.Dialer{Timeout: p.dialTimeout()}
+	conn, err := tls.DialWithDialer(dialer, "tcp", hostPort, &tls.Config{ServerName: p.cfg.Host})
+	if err != nil {
+		return fmt.Errorf("smtp ssl dial: %w", err)
+	}
+	defer conn.Close()
+
+	client, err := smtp.NewClient(conn, p.cfg.Host)
+	if err != nil {
+		return fmt.Errorf("smtp ssl client: %w", err)
+	}
+	defer client.Close()
+
+	if err := client.Auth(auth); err != nil {
+		return fmt.Errorf("smtp ssl auth: %w", err)
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// sendStartTLS dials hostPort with a bounded timeout (smtp.SendMail's
+// implicit net.Dial has none) and upgrades to TLS before authenticating.
+func (p *SMTPProvider) sendStartTLS(hostPort string, auth smtp.Auth, from, to string, msg []byte) error {
+	conn, err := net.DialTimeout("tcp", hostPort, p.dialTimeout())
+	if err != nil {
+		return fmt.Errorf("smtp starttls dial: %w", err)
+	}
+	defer conn.Close()
+
+	client, err := smtp.NewClient(conn, p.cfg.Host)
+	if err != nil {
+		return fmt.Errorf("smtp starttls client: %w", err)
+	}
+	defer client.Close()
+
+	if err := client.StartTLS(&tls.Config{ServerName: p.cfg.Host}); err != nil {
+		return fmt.Errorf("smtp starttls upgrade: %w", err)
+	}
+	if err := client.Auth(auth); err != nil {
+		return fmt.Errorf("smtp starttls auth: %w", err)
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// headerSafe strips CR/LF from a value before it's interpolated into a raw
+// RFC822 header line. Subject in particular flows straight from
+// templates.Render (text/template, no escaping), so a caller-supplied
+// variable containing "\r\n" could otherwise break out of its header line
+// and inject arbitrary extra headers (e.g. a Bcc:) into the message.
+func headerSafe(s string) string {
+	s = strings.ReplaceAll(s, "\r", "")
+	s = strings.ReplaceAll(s, "\n", "")
+	return s
+}
+
+// buildRFC822Message renders email as a multipart/alternative message when
+// it carries both an HTML and a text body (the normal case once templates
+// render both - see templates.Rendered), falling back to a single-part
+// message when only one is set.
+func buildRFC822Message(fromName, fromAddr, to string, email Email) []byte {
+	fromName, fromAddr, to = headerSafe(fromName), headerSafe(fromAddr), headerSafe(to)
+	subject := headerSafe(email.Subject)
+	header := fmt.Sprintf("From: %s <%s>\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\n",
+		fromName, fromAddr, to, subject)
+
+	if email.HTMLBody != "" && email.TextBody != "" {
+		return buildMultipartMessage(header, email)
+	}
+
+	body, contentType := email.HTMLBody, "text/html; charset=UTF-8"
+	if body == "" {
+		body, contentType = email.TextBody, "text/plain; charset=UTF-8"
+	}
+	return []byte(fmt.Sprintf("%sContent-Type: %s\r\n\r\n%s", header, contentType, body))
+}
+
+func buildMultipartMessage(header string, email Email) []byte {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	writePart := func(contentType, body string) {
+		part, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType}})
+		_, _ = part.Write([]byte(body))
+	}
+	// text/plain before text/html, per RFC 2046's "increasing order of
+	// preference" for multipart/alternative.
+	writePart("text/plain; charset=UTF-8", email.TextBody)
+	writePart("text/html; charset=UTF-8", email.HTMLBody)
+	_ = mw.Close()
+
+	msg := fmt.Sprintf("%sContent-Type: multipart/alternative; boundary=%q\r\n\r\n%s",
+		header, mw.Boundary(), buf.String())
+	return []byte(msg)
+}
+
+// MailgunProviderConfig configures the Mailgun-backed provider.
+type MailgunProviderConfig struct {
+	Domain   string
+	APIKey   string
+	FromName string
+	FromAddr string
+	Timeout  time.Duration
+}
+
+// MailgunClient is the subset of the Mailgun SDK the provider depends on.
+type MailgunClient interface {
+	Send(domain, from, to, subject, html, text string) (messageID string, err error)
+}
+
+// MailgunProvider sends email via the Mailgun HTTP API.
+type MailgunProvider struct {
+	client   MailgunClient
+	domain   string
+	fromName string
+	fromAddr string
+	timeout  time.Duration
+}
+
+// NewMailgunProvider creates a Mailgun-backed EmailProvider.
+func NewMailgunProvider(client MailgunClient, cfg MailgunProviderConfig) *MailgunProvider {
+	return &MailgunProvider{
+		client:   client,
+		domain:   cfg.Domain,
+		fromName: cfg.FromName,
+		fromAddr: cfg.FromAddr,
+		timeout:  cfg.Timeout,
+	}
+}
+
+func (p *MailgunProvider) Name() string { return "mailgun" }
+
+func (p *MailgunProvider) Send(addr, fromName, fromAddr string, email Email) (string, error) {
+	if fromName == "" {
+		fromName = p.fromName
+	}
+	if fromAddr == "" {
+		fromAddr = p.fromAddr
+	}
+	from := fmt.Sprintf("%s <%s>", fromName, fromAddr)
+	return callWithTimeout(p.timeout, func() (string, error) {
+		return p.client.Send(p.domain, from, addr, email.Subject, email.HTMLBody, email.TextBody)
+	})
+}
+
+// callWithTimeout bounds how long fn may run: ResendClient and
+// MailgunClient are plain SDK interfaces with no context parameter of
+// their own, so this is the only way cfg.Timeout can actually cut off a
+// slow call instead of sitting on the struct unused. A timeout leaves fn
+// running in the background; it's assumed to eventually return and its
+// result is discarded. timeout <= 0 disables the bound entirely.
+func callWithTimeout(timeout time.Duration, fn func() (string, error)) (string, error) {
+	if timeout <= 0 {
+		return fn()
+	}
+
+	type result struct {
+		id  string
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		id, err := fn()
+		done <- result{id, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.id, r.err
+	case <-time.After(timeout):
+		return "", fmt.Errorf("provider call timed out after %s", timeout)
+	}
+}
@@ -0,0 +1,69 @@
+package grpcserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// TelegramProvider delivers notifications via the Telegram Bot API's
+// sendMessage method.
+type TelegramProvider struct {
+	botToken   string
+	httpClient *http.Client
+}
+
+// NewTelegramProvider creates a provider authenticated with the given bot
+// token (from @BotFather).
+func NewTelegramProvider(botToken string) *TelegramProvider {
+	return &TelegramProvider{
+		botToken:   botToken,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type telegramSendMessageRequest struct {
+	ChatID string `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+type telegramSendMessageResponse struct {
+	OK     bool `json:"ok"`
+	Result struct {
+		MessageID int `json:"message_id"`
+	} `json:"result"`
+	Description string `json:"description"`
+}
+
+// SendMessage posts text to chatID, returning Telegram's message ID.
+func (p *TelegramProvider) SendMessage(chatID, text string) (messageID string, err error) {
+	body, err := json.Marshal(telegramSendMessageRequest{ChatID: chatID, Text: text})
+	if err != nil {
+		return "", fmt.Errorf("telegram: marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", p.botToken)
+	resp, err := p.httpClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("telegram: send message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("telegram: read response: %w", err)
+	}
+
+	var parsed telegramSendMessageResponse
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return "", fmt.Errorf("telegram: decode response: %w", err)
+	}
+	if !parsed.OK {
+		return "", fmt.Errorf("telegram: %s", parsed.Description)
+	}
+
+	return fmt.Sprintf("%d", parsed.Result.MessageID), nil
+}
@@ -0,0 +1,144 @@
+package grpcserver
+
+// TemplateServer implements template/v1's TemplateService: a registry of
+// versioned, localized templates that EmailServer and NotificationServer
+// render against, plus a render/preview pair so a frontend can show what a
+// template looks like before it's published or before it's used to send
+// anything.
+
+import (
+	"context"
+
+	templatev1 "github.com/Cloud9Money/maia/proto/template/v1"
+	"github.com/Cloud9Money/valar/internal/templates"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TemplateServer implements the TemplateService gRPC server.
+type TemplateServer struct {
+	templatev1.UnimplementedTemplateServiceServer
+	registry *templates.Registry
+	logger   Logger
+}
+
+// NewTemplateServer creates a TemplateServer backed by registry.
+func NewTemplateServer(registry *templates.Registry, logger Logger) *TemplateServer {
+	return &TemplateServer{registry: registry, logger: logger}
+}
+
+// RegisterTemplate implements the RegisterTemplate RPC, publishing a new
+// version of a template.
+func (s *TemplateServer) RegisterTemplate(ctx context.Context, req *templatev1.RegisterTemplateRequest) (*templatev1.RegisterTemplateResponse, error) {
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	if req.Version <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "version must be positive")
+	}
+
+	tmpl := &templates.Template{
+		ID:              req.Id,
+		Version:         int(req.Version),
+		Locale:          req.Locale,
+		Kind:            templates.Kind(req.Kind),
+		SubjectTemplate: req.SubjectTemplate,
+		HTMLTemplate:    req.HtmlTemplate,
+		TextTemplate:    req.TextTemplate,
+		VariablesSchema: []byte(req.VariablesSchemaJson),
+	}
+	if err := s.registry.Register(ctx, tmpl); err != nil {
+		return nil, status.Errorf(codes.Internal, "register template %s: %v", req.Id, err)
+	}
+
+	s.logger.Info("registered template", "id", req.Id, "version", req.Version, "locale", req.Locale)
+	return &templatev1.RegisterTemplateResponse{Success: true}, nil
+}
+
+// GetTemplate implements the GetTemplate RPC.
+func (s *TemplateServer) GetTemplate(ctx context.Context, req *templatev1.GetTemplateRequest) (*templatev1.GetTemplateResponse, error) {
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	tmpl, err := s.registry.Get(ctx, req.Id, req.Locale)
+	if err == templates.ErrNotFound {
+		return nil, status.Errorf(codes.NotFound, "no template %q for locale %q", req.Id, req.Locale)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "get template %s: %v", req.Id, err)
+	}
+
+	return &templatev1.GetTemplateResponse{Template: toProtoTemplate(tmpl)}, nil
+}
+
+// ListTemplates implements the ListTemplates RPC.
+func (s *TemplateServer) ListTemplates(ctx context.Context, req *templatev1.ListTemplatesRequest) (*templatev1.ListTemplatesResponse, error) {
+	all, err := s.registry.List(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "list templates: %v", err)
+	}
+
+	resp := &templatev1.ListTemplatesResponse{}
+	for _, tmpl := range all {
+		resp.Templates = append(resp.Templates, toProtoTemplate(tmpl))
+	}
+	return resp, nil
+}
+
+// RenderTemplate implements the RenderTemplate RPC: it renders the latest
+// version of a template against the caller's variables without sending
+// anything, for frontend preview use cases.
+func (s *TemplateServer) RenderTemplate(ctx context.Context, req *templatev1.RenderTemplateRequest) (*templatev1.RenderTemplateResponse, error) {
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	rendered, err := s.registry.Render(ctx, req.Id, req.Locale, req.Variables)
+	if err != nil {
+		return nil, mapRenderError(req.Id, err)
+	}
+
+	return &templatev1.RenderTemplateResponse{
+		Subject:  rendered.Subject,
+		HtmlBody: rendered.HTML,
+		TextBody: rendered.Text,
+	}, nil
+}
+
+// PreviewTemplate implements the PreviewTemplate RPC: like RenderTemplate,
+// but against a specific pinned version rather than the latest. The version
+// must already be registered; registering one above the current latest
+// makes it the version RenderTemplate resolves to.
+func (s *TemplateServer) PreviewTemplate(ctx context.Context, req *templatev1.PreviewTemplateRequest) (*templatev1.RenderTemplateResponse, error) {
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	if req.Version <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "version must be positive")
+	}
+
+	rendered, err := s.registry.Preview(ctx, req.Id, int(req.Version), req.Locale, req.Variables)
+	if err != nil {
+		return nil, mapRenderError(req.Id, err)
+	}
+
+	return &templatev1.RenderTemplateResponse{
+		Subject:  rendered.Subject,
+		HtmlBody: rendered.HTML,
+		TextBody: rendered.Text,
+	}, nil
+}
+
+func toProtoTemplate(tmpl *templates.Template) *templatev1.Template {
+	return &templatev1.Template{
+		Id:                  tmpl.ID,
+		Version:             int32(tmpl.Version),
+		Locale:              tmpl.Locale,
+		Kind:                string(tmpl.Kind),
+		SubjectTemplate:     tmpl.SubjectTemplate,
+		HtmlTemplate:        tmpl.HTMLTemplate,
+		TextTemplate:        tmpl.TextTemplate,
+		VariablesSchemaJson: string(tmpl.VariablesSchema),
+	}
+}
@@ -0,0 +1,36 @@
+package grpcserver
+
+import (
+	"github.com/Cloud9Money/valar/internal/templates"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Built-in template IDs rendered by EmailServer's fixed Send* RPCs. Each
+// must be registered in the templates.Registry before its RPC can be
+// called; SendTemplateEmail and SendNotification render caller-supplied
+// IDs instead of these constants.
+const (
+	TemplateVerification            = "verification-email"
+	TemplatePasswordReset           = "password-reset"
+	TemplateWelcome                 = "welcome-email"
+	TemplateTransactionNotification = "transaction-notification"
+)
+
+// defaultLocale is used when a caller doesn't specify one; request 6 adds
+// an explicit Options.locale field for callers that need another one.
+const defaultLocale = "en"
+
+// mapRenderError maps a templates.Registry render/preview error onto the
+// gRPC status code a caller should see: a missing template is NotFound,
+// missing variables are the caller's mistake (InvalidArgument), anything
+// else is an Internal rendering failure.
+func mapRenderError(id string, err error) error {
+	if err == templates.ErrNotFound {
+		return status.Errorf(codes.NotFound, "no template %q", id)
+	}
+	if missing, ok := err.(*templates.MissingVariablesError); ok {
+		return status.Errorf(codes.InvalidArgument, "%s", missing.Error())
+	}
+	return status.Errorf(codes.Internal, "render template %s: %v", id, err)
+}
@@ -0,0 +1,131 @@
+package templates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// FSStore is a Store backed by a directory of one JSON file per template
+// version, useful for local development and for environments that manage
+// templates via config rather than a database. Each file is named
+// "<id>.v<version>.<locale>.json" and holds a JSON-encoded Template.
+type FSStore struct {
+	dir string
+
+	mu    sync.RWMutex
+	cache map[string]*Template // keyed by fileKey(id, version, locale)
+}
+
+// NewFSStore creates an FSStore rooted at dir, loading every "*.json" file
+// found there into an in-memory cache.
+func NewFSStore(dir string) (*FSStore, error) {
+	s := &FSStore{dir: dir, cache: make(map[string]*Template)}
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FSStore) reload() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("templates: read %s: %w", s.dir, err)
+	}
+
+	cache := make(map[string]*Template)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("templates: read %s: %w", entry.Name(), err)
+		}
+		var tmpl Template
+		if err := json.Unmarshal(data, &tmpl); err != nil {
+			return fmt.Errorf("templates: parse %s: %w", entry.Name(), err)
+		}
+		cache[fileKey(tmpl.ID, tmpl.Version, tmpl.Locale)] = &tmpl
+	}
+
+	s.mu.Lock()
+	s.cache = cache
+	s.mu.Unlock()
+	return nil
+}
+
+func fileKey(id string, version int, locale string) string {
+	return fmt.Sprintf("%s|%d|%s", id, version, locale)
+}
+
+// Get returns the highest-versioned template matching id and locale.
+func (s *FSStore) Get(ctx context.Context, id, locale string) (*Template, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var latest *Template
+	for _, tmpl := range s.cache {
+		if tmpl.ID != id || tmpl.Locale != locale {
+			continue
+		}
+		if latest == nil || tmpl.Version > latest.Version {
+			latest = tmpl
+		}
+	}
+	if latest == nil {
+		return nil, ErrNotFound
+	}
+	return latest, nil
+}
+
+// GetVersion returns the exact id/version/locale, if loaded.
+func (s *FSStore) GetVersion(ctx context.Context, id string, version int, locale string) (*Template, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if tmpl, ok := s.cache[fileKey(id, version, locale)]; ok {
+		return tmpl, nil
+	}
+	return nil, ErrNotFound
+}
+
+// List returns every loaded template, sorted by id then version.
+func (s *FSStore) List(ctx context.Context) ([]*Template, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*Template, 0, len(s.cache))
+	for _, tmpl := range s.cache {
+		out = append(out, tmpl)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].ID != out[j].ID {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].Version < out[j].Version
+	})
+	return out, nil
+}
+
+// Put writes tmpl to "<id>.v<version>.<locale>.json" and updates the cache.
+func (s *FSStore) Put(ctx context.Context, tmpl *Template) error {
+	data, err := json.MarshalIndent(tmpl, "", "  ")
+	if err != nil {
+		return fmt.Errorf("templates: marshal %s: %w", tmpl.ID, err)
+	}
+
+	name := fmt.Sprintf("%s.v%d.%s.json", tmpl.ID, tmpl.Version, tmpl.Locale)
+	if err := os.WriteFile(filepath.Join(s.dir, name), data, 0o644); err != nil {
+		return fmt.Errorf("templates: write %s: %w", name, err)
+	}
+
+	s.mu.Lock()
+	s.cache[fileKey(tmpl.ID, tmpl.Version, tmpl.Locale)] = tmpl
+	s.mu.Unlock()
+	return nil
+}
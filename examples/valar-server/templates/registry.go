@@ -0,0 +1,212 @@
+// Package templates implements a versioned, localized registry for the
+// subject/HTML/text templates EmailServer and NotificationServer render
+// before sending, replacing hardcoded template-ID strings with a lookup
+// against a Store (see FSStore and the SQL-backed stores).
+package templates
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// ErrNotFound is returned by a Store when no template matches the given
+// id/version/locale.
+var ErrNotFound = errors.New("templates: not found")
+
+// Kind classifies what a template is used for, mirroring the built-in
+// Send* RPCs that render one; custom templates registered through
+// RegisterTemplate use KindCustom.
+type Kind string
+
+const (
+	KindVerification  Kind = "verification"
+	KindPasswordReset Kind = "password_reset"
+	KindWelcome       Kind = "welcome"
+	KindTransaction   Kind = "transaction"
+	KindCustom        Kind = "custom"
+)
+
+// Template is one registered, renderable version of a template.
+type Template struct {
+	ID              string
+	Version         int
+	Locale          string
+	Kind            Kind
+	SubjectTemplate string
+	HTMLTemplate    string
+	TextTemplate    string
+	// VariablesSchema is a JSON Schema subset (see Schema) describing the
+	// variables this template requires. Empty means no validation.
+	VariablesSchema json.RawMessage
+	CreatedAt       time.Time
+}
+
+// Store persists templates and resolves the right version/locale to
+// render. Get resolves the latest version for a locale; GetVersion pins
+// an exact registered version, for previewing one other than the latest.
+type Store interface {
+	Get(ctx context.Context, id, locale string) (*Template, error)
+	GetVersion(ctx context.Context, id string, version int, locale string) (*Template, error)
+	List(ctx context.Context) ([]*Template, error)
+	Put(ctx context.Context, tmpl *Template) error
+}
+
+// Schema is the minimal JSON Schema subset a template's VariablesSchema
+// is parsed as: enough to validate a caller's variables map without
+// pulling in a full JSON Schema implementation.
+type Schema struct {
+	Required   []string            `json:"required"`
+	Properties map[string]Property `json:"properties"`
+}
+
+// Property describes one variable's expected type. validateVariables does
+// not consult it (only Schema.Required is checked), since template
+// variables are always rendered as strings; it exists so schemas stay
+// forward-compatible with a real validator later.
+type Property struct {
+	Type string `json:"type"`
+}
+
+// MissingVariablesError reports the required variables a render call's
+// variables map was missing.
+type MissingVariablesError struct {
+	Missing []string
+}
+
+func (e *MissingVariablesError) Error() string {
+	return fmt.Sprintf("templates: missing required variables: %s", strings.Join(e.Missing, ", "))
+}
+
+// Rendered is the output of rendering a Template against a variables map.
+type Rendered struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Registry resolves templates from a Store and renders them.
+type Registry struct {
+	store Store
+}
+
+// NewRegistry creates a Registry backed by store.
+func NewRegistry(store Store) *Registry {
+	return &Registry{store: store}
+}
+
+// Register publishes tmpl as a new version in the registry.
+func (r *Registry) Register(ctx context.Context, tmpl *Template) error {
+	if tmpl.ID == "" {
+		return fmt.Errorf("templates: id is required")
+	}
+	if tmpl.Version <= 0 {
+		return fmt.Errorf("templates: version must be positive")
+	}
+	return r.store.Put(ctx, tmpl)
+}
+
+// Get returns the latest version of template id in locale.
+func (r *Registry) Get(ctx context.Context, id, locale string) (*Template, error) {
+	return r.store.Get(ctx, id, locale)
+}
+
+// List returns every registered template version.
+func (r *Registry) List(ctx context.Context) ([]*Template, error) {
+	return r.store.List(ctx)
+}
+
+// Render resolves the latest version of template id in locale and renders
+// it against variables, validating variables against the template's
+// schema first.
+func (r *Registry) Render(ctx context.Context, id, locale string, variables map[string]string) (*Rendered, error) {
+	tmpl, err := r.store.Get(ctx, id, locale)
+	if err != nil {
+		return nil, err
+	}
+	return render(tmpl, variables)
+}
+
+// Preview renders a specific pinned version of template id rather than the
+// latest one Render resolves to. Only registered versions can be
+// previewed; Register publishes a version directly, with no draft state.
+func (r *Registry) Preview(ctx context.Context, id string, version int, locale string, variables map[string]string) (*Rendered, error) {
+	tmpl, err := r.store.GetVersion(ctx, id, version, locale)
+	if err != nil {
+		return nil, err
+	}
+	return render(tmpl, variables)
+}
+
+func render(tmpl *Template, variables map[string]string) (*Rendered, error) {
+	if err := validateVariables(tmpl.VariablesSchema, variables); err != nil {
+		return nil, err
+	}
+
+	subject, err := renderText(tmpl.ID+":subject", tmpl.SubjectTemplate, variables)
+	if err != nil {
+		return nil, err
+	}
+	html, err := renderHTML(tmpl.ID+":html", tmpl.HTMLTemplate, variables)
+	if err != nil {
+		return nil, err
+	}
+	plainText, err := renderText(tmpl.ID+":text", tmpl.TextTemplate, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Rendered{Subject: subject, HTML: html, Text: plainText}, nil
+}
+
+func renderHTML(name, src string, variables map[string]string) (string, error) {
+	t, err := template.New(name).Parse(src)
+	if err != nil {
+		return "", fmt.Errorf("templates: parse %s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, variables); err != nil {
+		return "", fmt.Errorf("templates: render %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+func renderText(name, src string, variables map[string]string) (string, error) {
+	t, err := texttemplate.New(name).Parse(src)
+	if err != nil {
+		return "", fmt.Errorf("templates: parse %s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, variables); err != nil {
+		return "", fmt.Errorf("templates: render %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+func validateVariables(schema json.RawMessage, variables map[string]string) error {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	var s Schema
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return fmt.Errorf("templates: invalid variables schema: %w", err)
+	}
+
+	var missing []string
+	for _, key := range s.Required {
+		if _, ok := variables[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingVariablesError{Missing: missing}
+	}
+	return nil
+}
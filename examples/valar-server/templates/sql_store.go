@@ -0,0 +1,133 @@
+package templates
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// dialect captures the small SQL differences between Postgres and SQLite
+// that sqlStore needs to account for (placeholder syntax, upsert clause).
+type dialect struct {
+	name        string
+	placeholder func(n int) string
+	upsert      string
+}
+
+var postgresDialect = dialect{
+	name:        "postgres",
+	placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+	upsert: "ON CONFLICT (id, version, locale) DO UPDATE SET " +
+		"subject_template = EXCLUDED.subject_template, html_template = EXCLUDED.html_template, " +
+		"text_template = EXCLUDED.text_template, variables_schema = EXCLUDED.variables_schema",
+}
+
+var sqliteDialect = dialect{
+	name:        "sqlite",
+	placeholder: func(n int) string { return "?" },
+	upsert: "ON CONFLICT(id, version, locale) DO UPDATE SET " +
+		"subject_template = excluded.subject_template, html_template = excluded.html_template, " +
+		"text_template = excluded.text_template, variables_schema = excluded.variables_schema",
+}
+
+// sqlStore is a database/sql-backed Store shared by the Postgres and
+// SQLite flavors; only the dialect differs between them.
+type sqlStore struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+// PostgresStore is a Store backed by a Postgres `email_templates` table.
+type PostgresStore struct{ *sqlStore }
+
+// NewPostgresStore wraps an existing *sql.DB.
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{&sqlStore{db: db, dialect: postgresDialect}}
+}
+
+// SQLiteStore is a Store backed by a SQLite `email_templates` table.
+type SQLiteStore struct{ *sqlStore }
+
+// NewSQLiteStore wraps an existing *sql.DB.
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return &SQLiteStore{&sqlStore{db: db, dialect: sqliteDialect}}
+}
+
+func (s *sqlStore) Get(ctx context.Context, id, locale string) (*Template, error) {
+	query := fmt.Sprintf(`
+		SELECT id, version, locale, kind, subject_template, html_template, text_template, variables_schema, created_at
+		FROM email_templates WHERE id = %s AND locale = %s
+		ORDER BY version DESC LIMIT 1`,
+		s.dialect.placeholder(1), s.dialect.placeholder(2))
+
+	return scanRow(s.db.QueryRowContext(ctx, query, id, locale))
+}
+
+func (s *sqlStore) GetVersion(ctx context.Context, id string, version int, locale string) (*Template, error) {
+	query := fmt.Sprintf(`
+		SELECT id, version, locale, kind, subject_template, html_template, text_template, variables_schema, created_at
+		FROM email_templates WHERE id = %s AND version = %s AND locale = %s`,
+		s.dialect.placeholder(1), s.dialect.placeholder(2), s.dialect.placeholder(3))
+
+	return scanRow(s.db.QueryRowContext(ctx, query, id, version, locale))
+}
+
+func scanRow(row *sql.Row) (*Template, error) {
+	var t Template
+	var kind, schema sql.NullString
+	err := row.Scan(&t.ID, &t.Version, &t.Locale, &kind, &t.SubjectTemplate, &t.HTMLTemplate, &t.TextTemplate,
+		&schema, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("templates: scan: %w", err)
+	}
+	t.Kind = Kind(kind.String)
+	t.VariablesSchema = json.RawMessage(schema.String)
+	return &t, nil
+}
+
+func (s *sqlStore) List(ctx context.Context) ([]*Template, error) {
+	query := `
+		SELECT id, version, locale, kind, subject_template, html_template, text_template, variables_schema, created_at
+		FROM email_templates ORDER BY id ASC, version ASC`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("templates: list: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*Template
+	for rows.Next() {
+		var t Template
+		var kind, schema sql.NullString
+		if err := rows.Scan(&t.ID, &t.Version, &t.Locale, &kind, &t.SubjectTemplate, &t.HTMLTemplate, &t.TextTemplate,
+			&schema, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("templates: scan row: %w", err)
+		}
+		t.Kind = Kind(kind.String)
+		t.VariablesSchema = json.RawMessage(schema.String)
+		out = append(out, &t)
+	}
+	return out, rows.Err()
+}
+
+func (s *sqlStore) Put(ctx context.Context, tmpl *Template) error {
+	query := fmt.Sprintf(`
+		INSERT INTO email_templates (id, version, locale, kind, subject_template, html_template, text_template, variables_schema, created_at)
+		VALUES (%s, %s, %s, %s, %s, %s, %s, %s, %s) %s`,
+		s.dialect.placeholder(1), s.dialect.placeholder(2), s.dialect.placeholder(3), s.dialect.placeholder(4),
+		s.dialect.placeholder(5), s.dialect.placeholder(6), s.dialect.placeholder(7), s.dialect.placeholder(8),
+		s.dialect.placeholder(9), s.dialect.upsert)
+
+	_, err := s.db.ExecContext(ctx, query, tmpl.ID, tmpl.Version, tmpl.Locale, string(tmpl.Kind),
+		tmpl.SubjectTemplate, tmpl.HTMLTemplate, tmpl.TextTemplate, string(tmpl.VariablesSchema), time.Now())
+	if err != nil {
+		return fmt.Errorf("templates: put %s: %w", tmpl.ID, err)
+	}
+	return nil
+}
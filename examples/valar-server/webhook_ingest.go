@@ -0,0 +1,228 @@
+package grpcserver
+
+// Webhook ingestion for provider delivery callbacks (Resend, Mailgun,
+// SES/SNS). Each handler verifies the provider's signature, normalizes the
+// payload into a DeliveryEvent, and hands it to DeliveryEventsServer.
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// WebhookHandler verifies and normalizes inbound delivery webhooks from
+// every supported provider.
+type WebhookHandler struct {
+	events            *DeliveryEventsServer
+	resendSecret      string
+	mailgunSigningKey string
+	logger            Logger
+}
+
+// NewWebhookHandler creates a handler that ingests into events, verifying
+// against the given per-provider secrets.
+func NewWebhookHandler(events *DeliveryEventsServer, resendSecret, mailgunSigningKey string, logger Logger) *WebhookHandler {
+	return &WebhookHandler{
+		events:            events,
+		resendSecret:      resendSecret,
+		mailgunSigningKey: mailgunSigningKey,
+		logger:            logger,
+	}
+}
+
+type resendWebhookPayload struct {
+	Type string `json:"type"` // e.g. "email.delivered"
+	Data struct {
+		EmailID string `json:"email_id"`
+	} `json:"data"`
+}
+
+var resendEventTypes = map[string]DeliveryEventType{
+	"email.sent":       EventSent,
+	"email.delivered":  EventDelivered,
+	"email.bounced":    EventBounced,
+	"email.complained": EventComplained,
+	"email.opened":     EventOpened,
+}
+
+// HandleResend ingests a Resend webhook, verified via the svix-style
+// HMAC-SHA256 signature Resend sends in the Resend-Signature header.
+func (h *WebhookHandler) HandleResend(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	if !verifyHMACSignature(h.resendSecret, body, r.Header.Get("Resend-Signature")) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var payload resendWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	eventType, ok := resendEventTypes[payload.Type]
+	if !ok {
+		w.WriteHeader(http.StatusOK) // unrecognized event types are not an error
+		return
+	}
+
+	if err := h.events.Ingest(r.Context(), DeliveryEvent{
+		MessageID: payload.Data.EmailID,
+		Type:      eventType,
+		Timestamp: time.Now(),
+	}); err != nil {
+		http.Error(w, "failed to record event", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+type mailgunWebhookPayload struct {
+	Signature struct {
+		Timestamp string `json:"timestamp"`
+		Token     string `json:"token"`
+		Signature string `json:"signature"`
+	} `json:"signature"`
+	EventData struct {
+		Event   string `json:"event"`
+		Message struct {
+			Headers struct {
+				MessageID string `json:"message-id"`
+			} `json:"headers"`
+		} `json:"message"`
+	} `json:"event-data"`
+}
+
+var mailgunEventTypes = map[string]DeliveryEventType{
+	"delivered":  EventDelivered,
+	"bounced":    EventBounced,
+	"complained": EventComplained,
+	"opened":     EventOpened,
+}
+
+// HandleMailgun ingests a Mailgun webhook, verified per Mailgun's
+// timestamp+token HMAC-SHA256 scheme.
+func (h *WebhookHandler) HandleMailgun(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	var payload mailgunWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	signed := payload.Signature.Timestamp + payload.Signature.Token
+	if !verifyHMACSignature(h.mailgunSigningKey, []byte(signed), payload.Signature.Signature) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	eventType, ok := mailgunEventTypes[payload.EventData.Event]
+	if !ok {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	ts, _ := strconv.ParseInt(payload.Signature.Timestamp, 10, 64)
+	if err := h.events.Ingest(r.Context(), DeliveryEvent{
+		MessageID: payload.EventData.Message.Headers.MessageID,
+		Type:      eventType,
+		Timestamp: time.Unix(ts, 0),
+	}); err != nil {
+		http.Error(w, "failed to record event", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleSES ingests an SES bounce/complaint/delivery notification
+// delivered via an SNS subscription, verified against the per-message
+// certificate and RSA signature SNS attaches (see verifySNSSignature).
+func (h *WebhookHandler) HandleSES(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	var notification snsNotification
+	if err := json.Unmarshal(body, &notification); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	if notification.Type != "Notification" {
+		// SubscriptionConfirmation/UnsubscribeConfirmation are one-time
+		// setup actions with their own string-to-sign format, not delivery
+		// events; confirm subscriptions out of band instead of trusting an
+		// unverified SubscribeURL here.
+		h.logger.Info("ignoring non-Notification SNS message", "type", notification.Type)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if err := verifySNSSignature(notification); err != nil {
+		h.logger.Error("SNS signature verification failed", "error", err)
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var ses struct {
+		EventType string `json:"eventType"`
+		Mail      struct {
+			MessageID string `json:"messageId"`
+		} `json:"mail"`
+	}
+	if err := json.Unmarshal([]byte(notification.Message), &ses); err != nil {
+		http.Error(w, "invalid SES message", http.StatusBadRequest)
+		return
+	}
+
+	eventType, ok := map[string]DeliveryEventType{
+		"Delivery":  EventDelivered,
+		"Bounce":    EventBounced,
+		"Complaint": EventComplained,
+		"Open":      EventOpened,
+	}[ses.EventType]
+	if !ok {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if err := h.events.Ingest(r.Context(), DeliveryEvent{
+		MessageID: ses.Mail.MessageID,
+		Type:      eventType,
+		Timestamp: time.Now(),
+	}); err != nil {
+		http.Error(w, "failed to record event", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// verifyHMACSignature reports whether signatureHex is the hex-encoded
+// HMAC-SHA256 of payload under secret, in constant time.
+func verifyHMACSignature(secret string, payload []byte, signatureHex string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(signatureHex)) == 1
+}